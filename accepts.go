@@ -0,0 +1,318 @@
+package pifra
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prettyLabelPattern matches a label in the pretty-printed format, e.g., 1 2*
+// or 1'1^.
+var prettyLabelPattern = regexp.MustCompile(`^[0-9]+( |')[0-9]+[*^]?$`)
+
+// namedLabelPattern matches a label with names, e.g., a b or a'x^, whose names
+// are lexed as those of a model.
+var namedLabelPattern = regexp.MustCompile(`^([_]?[a-zA-Z0-9]+)( |')([_]?[a-zA-Z0-9]+)([*^]?)$`)
+
+// traceLabel is a label of a trace to be accepted by a model, from a line of
+// the trace file.
+type traceLabel struct {
+	Line int
+	Text string
+	Tau  bool
+
+	// A label with names: the channel and object names, whether it is an
+	// output, and whether the object is marked as fresh.
+	Channel string
+	Object  string
+	Output  bool
+	Fresh   bool
+}
+
+// acceptItem is a configuration reached by a prefix of a trace, with the
+// register labels of the names of a trace with names, and the path by which
+// it was reached with the derivation of each transition, if the transitions
+// are explained.
+type acceptItem struct {
+	Conf        Configuration
+	Names       map[string]int
+	Path        []Transition
+	Derivations []Provenance
+}
+
+// acceptResult is the result of an acceptance check: the number of labels
+// accepted, and the configurations reached by the longest prefix accepted,
+// numbered in the order they were first reached.
+type acceptResult struct {
+	Accepted int
+	Items    []acceptItem
+	States   map[int]Configuration
+}
+
+// parseTrace parses a trace with a label per line, either all in the
+// pretty-printed format or all with names. Blank lines are skipped and t is
+// the label of a τ-transition in both. It returns whether the trace has
+// names.
+func parseTrace(input string) ([]traceLabel, bool, error) {
+	var labels []traceLabel
+	pretty := true
+	for i, line := range strings.Split(input, "\n") {
+		text := strings.Join(strings.Fields(line), " ")
+		if text == "" {
+			continue
+		}
+		label := traceLabel{
+			Line: i + 1,
+			Text: text,
+			Tau:  text == "t",
+		}
+		if !label.Tau {
+			match := namedLabelPattern.FindStringSubmatch(text)
+			if match == nil {
+				return nil, false, fmt.Errorf("line %d: %q is not a label, e.g., a b, a'b, 1 2* or t", i+1, text)
+			}
+			label.Channel = match[1]
+			label.Output = match[2] == "'"
+			label.Object = match[3]
+			label.Fresh = match[4] != ""
+			pretty = pretty && prettyLabelPattern.MatchString(text)
+		}
+		labels = append(labels, label)
+	}
+	return labels, !pretty, nil
+}
+
+// getRootLabels returns the register labels of the original names of the
+// root.
+func getRootLabels(root Configuration, namesMap map[string]string) map[string]int {
+	names := make(map[string]int)
+	for label, name := range root.Registers.Registers {
+		if original, ok := namesMap[name]; ok {
+			name = original
+		}
+		names[name] = label
+	}
+	return names
+}
+
+// matchTraceStep returns whether a transition from a state has a label of a
+// trace. For a trace with names, it also returns the register labels of the
+// names after the transition, where a name which is not in the register of
+// the state is fresh.
+func matchTraceStep(state Configuration, conf Configuration, names map[string]int,
+	label traceLabel) (bool, map[string]int) {
+	if label.Tau || conf.Label.Symbol.Type == SymbolTypTau {
+		return label.Tau && conf.Label.Symbol.Type == SymbolTypTau, names
+	}
+	if names == nil {
+		return strings.TrimSpace(PrettyPrintLabel(conf.Label)) == label.Text, nil
+	}
+
+	if label.Output != (conf.Label.Symbol.Type == SymbolTypOutput) {
+		return false, nil
+	}
+	known := func(name string) (int, bool) {
+		id, ok := names[name]
+		if !ok {
+			return 0, false
+		}
+		_, ok = state.Registers.Registers[id]
+		return id, ok
+	}
+	if id, ok := known(label.Channel); !ok || id != conf.Label.Symbol.Value {
+		return false, nil
+	}
+	object := conf.Label.Symbol2
+	id, isKnown := known(label.Object)
+	switch object.Type {
+	case SymbolTypKnown:
+		if !isKnown || label.Fresh || id != object.Value {
+			return false, nil
+		}
+		return true, names
+	case SymbolTypFreshInput, SymbolTypFreshOutput:
+		if isKnown {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	// The fresh name takes a register label, which may be that of a name
+	// forgotten by the transition.
+	succNames := make(map[string]int)
+	for name, id := range names {
+		if id != object.Value {
+			succNames[name] = id
+		}
+	}
+	succNames[label.Object] = object.Value
+	return true, succNames
+}
+
+// getAcceptKey returns the key of a configuration reached by a prefix of a
+// trace, with the register labels of the names.
+func getAcceptKey(conf Configuration, names map[string]int) string {
+	var pairs []string
+	for name, id := range names {
+		if _, ok := conf.Registers.Registers[id]; ok {
+			pairs = append(pairs, name+"="+strconv.Itoa(id))
+		}
+	}
+	sort.Strings(pairs)
+	return getConfigurationKey(conf) + strings.Join(pairs, ",")
+}
+
+// checkAcceptance searches on the fly for the configurations reached from the
+// root by each prefix of a trace, until it is accepted or blocked.
+func checkAcceptance(root Configuration, namesMap map[string]string, labels []traceLabel,
+	named bool) acceptResult {
+	initTransCache()
+	defer func() {
+		transCache = nil
+	}()
+
+	applyStructrualCongruence(root)
+	root = initKnowledge(root)
+	ids := map[string]int{getConfigurationKey(root): 0}
+	states := map[int]Configuration{0: root}
+	getId := func(conf Configuration) int {
+		key := getConfigurationKey(conf)
+		if _, ok := ids[key]; !ok {
+			ids[key] = len(ids)
+			states[ids[key]] = conf
+		}
+		return ids[key]
+	}
+
+	rootItem := acceptItem{Conf: root}
+	if named {
+		rootItem.Names = getRootLabels(root, namesMap)
+	}
+	items := []acceptItem{rootItem}
+	for i, label := range labels {
+		var succItems []acceptItem
+		seen := make(map[string]bool)
+		for _, item := range items {
+			if len(item.Conf.Registers.Registers) > registerSize {
+				continue
+			}
+			for _, succ := range getSuccessors(item.Conf) {
+				ok, names := matchTraceStep(item.Conf, succ, item.Names, label)
+				if !ok {
+					continue
+				}
+				key := getAcceptKey(succ, names)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				trn := Transition{
+					Source:      getId(item.Conf),
+					Destination: getId(succ),
+					Label:       succ.Label,
+				}
+				succItem := acceptItem{
+					Conf:  succ,
+					Names: names,
+					Path:  append(append([]Transition{}, item.Path...), trn),
+				}
+				if explainTransitions {
+					succItem.Derivations = append(append([]Provenance{}, item.Derivations...),
+						succ.Provenance)
+				}
+				succItems = append(succItems, succItem)
+			}
+		}
+		if len(succItems) == 0 {
+			return acceptResult{
+				Accepted: i,
+				Items:    items,
+				States:   states,
+			}
+		}
+		items = succItems
+	}
+	return acceptResult{
+		Accepted: len(labels),
+		Items:    items,
+		States:   states,
+	}
+}
+
+// getAcceptNamedLabel returns a label of a transition from a state with the
+// names of a trace, where a fresh name is written new.
+func getAcceptNamedLabel(state Configuration, label Label, names map[string]int) string {
+	if label.Symbol.Type == SymbolTypTau {
+		return "t"
+	}
+	labelNames := make(map[int]string)
+	for name, id := range names {
+		if _, ok := state.Registers.Registers[id]; ok {
+			labelNames[id] = name
+		}
+	}
+	str := labelNames[label.Symbol.Value]
+	if label.Symbol.Type == SymbolTypOutput {
+		str = str + "'"
+	} else {
+		str = str + " "
+	}
+	switch label.Symbol2.Type {
+	case SymbolTypFreshInput:
+		return str + "new*"
+	case SymbolTypFreshOutput:
+		return str + "new^"
+	}
+	return str + labelNames[label.Symbol2.Value]
+}
+
+// generateAcceptReport returns whether a trace is accepted, with a path by
+// which it is accepted, or else the longest prefix accepted with a path by
+// which it is, and the transitions enabled after it.
+func generateAcceptReport(result acceptResult, labels []traceLabel, named bool) string {
+	var lines []string
+	path := result.Items[0].Path
+	trace := make([]string, len(path))
+	for i, trn := range path {
+		trace[i] = PrettyPrintLabel(trn.Label)
+	}
+	if named {
+		// The labels of the trace name the fresh names.
+		for i := range path {
+			trace[i] = labels[i].Text
+		}
+	}
+	for i, trn := range path {
+		lines = append(lines, "s"+strconv.Itoa(trn.Source)+"  "+trace[i]+"  s"+strconv.Itoa(trn.Destination))
+	}
+	if result.Accepted == len(labels) {
+		return strings.Join(lines, "\n")
+	}
+
+	label := labels[result.Accepted]
+	lines = append(lines, "line "+strconv.Itoa(label.Line)+": "+label.Text+" is not enabled")
+	for _, item := range result.Items {
+		conf := item.Conf
+		id := 0
+		if len(item.Path) != 0 {
+			id = item.Path[len(item.Path)-1].Destination
+		}
+		lines = append(lines, "s"+strconv.Itoa(id)+" = "+PrettyPrintRegister(conf.Registers)+" |- "+
+			PrettyPrintAst(conf.Process))
+		if len(conf.Registers.Registers) > registerSize {
+			lines = append(lines, "  register size reached")
+			continue
+		}
+		for _, succ := range getSuccessors(conf) {
+			if named {
+				lines = append(lines, "  "+getAcceptNamedLabel(conf, succ.Label, item.Names))
+			} else {
+				lines = append(lines, "  "+strings.TrimSpace(PrettyPrintLabel(succ.Label)))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,127 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestCheckAcceptance(t *testing.T) {
+	server := []byte(`Server(req) = req(c).$s.c'<s>.s(x).Server(req)
+Server(req)`)
+	tests := map[string]struct {
+		input    []byte
+		trace    string
+		named    bool
+		accepted int
+	}{
+		"pretty": {
+			input:    server,
+			trace:    "1 2*\n2'2^\n2 1\n1 1",
+			accepted: 4,
+		},
+		"pretty_rejected": {
+			input:    server,
+			trace:    "1 2*\n2'3^\n2 1",
+			accepted: 1,
+		},
+		"named_fresh_names": {
+			input:    server,
+			trace:    "req c1\n\nc1'sess\nsess hello\nreq c2\nc2'other^",
+			named:    true,
+			accepted: 5,
+		},
+		"named_known_name": {
+			input:    server,
+			trace:    "req req\nreq'sess\nsess req",
+			named:    true,
+			accepted: 3,
+		},
+		"named_fresh_name_known": {
+			input:    server,
+			trace:    "req c\nc'req",
+			named:    true,
+			accepted: 1,
+		},
+		"named_fresh_marked_known": {
+			input:    server,
+			trace:    "req req*",
+			named:    true,
+			accepted: 0,
+		},
+		"named_forgotten_name": {
+			input:    server,
+			trace:    "req c\nc'sess\nsess x\nsess'y",
+			named:    true,
+			accepted: 3,
+		},
+		"nondeterminism": {
+			input:    []byte(`a(x).b'<x>.0 + a(y).c'<y>.0`),
+			trace:    "a a\nc'a",
+			named:    true,
+			accepted: 2,
+		},
+		"tau": {
+			input:    []byte(`$c.(c'<a>.0 | c(x).x'<x>.0)`),
+			trace:    "t\na'a",
+			named:    true,
+			accepted: 2,
+		},
+		"beyond_max_states": {
+			input: []byte(`P(a) = a(x).P(x)
+P(a)`),
+			trace:    "a b\nb c\nc d\nd e\ne f",
+			named:    true,
+			accepted: 5,
+		},
+	}
+	maxStatesExplored = 1
+	registerSize = 1073741824
+	defer func() {
+		maxStatesExplored = 100
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			labels, named, err := parseTrace(test.trace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if named != test.named {
+				t.Errorf("got named %t, want %t", named, test.named)
+			}
+			proc, err := InitProgram(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, namesMap := newRootConf(proc)
+			result := checkAcceptance(root, namesMap, labels, named)
+			if result.Accepted != test.accepted {
+				t.Errorf("got %d labels accepted, want %d", result.Accepted, test.accepted)
+			}
+		})
+	}
+}
+
+func TestParseTrace(t *testing.T) {
+	labels, named, err := parseTrace("a  b*\nt\n\nx1'_BAD^")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !named || len(labels) != 3 {
+		t.Fatalf("got %d labels, named %t, want 3 named", len(labels), named)
+	}
+	if label := labels[0]; label.Text != "a b*" || label.Channel != "a" || label.Object != "b" ||
+		label.Output || !label.Fresh {
+		t.Errorf("got label %+v", label)
+	}
+	if label := labels[2]; label.Line != 4 || label.Channel != "x1" || label.Object != "_BAD" || !label.Output {
+		t.Errorf("got label %+v", label)
+	}
+	if !labels[1].Tau {
+		t.Errorf("got label %+v, want τ", labels[1])
+	}
+	for _, input := range []string{"a", "a'<b>", "a b c", "#1'a"} {
+		if _, _, err := parseTrace(input); err == nil {
+			t.Errorf("%q: got no error", input)
+		}
+	}
+}
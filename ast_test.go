@@ -160,6 +160,7 @@ a(b).$a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 						Name: "&b_0",
 						Type: Bound,
 					},
+					Position: Position{Line: 2, Column: 1},
 					Next: &ElemRestriction{
 						Restrict: Name{
 							Name: "&a_1",
@@ -174,6 +175,7 @@ a(b).$a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 								Name: "&a_2",
 								Type: Bound,
 							},
+							Position: Position{Line: 2, Column: 9},
 							Next: &ElemRestriction{
 								Restrict: Name{
 									Name: "&a_3",
@@ -189,7 +191,8 @@ a(b).$a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 											Name: "&a_3",
 											Type: Bound,
 										},
-										Next: &ElemNil{},
+										Position: Position{Line: 2, Column: 18},
+										Next:     &ElemNil{},
 									},
 									ProcessR: &ElemRestriction{
 										Restrict: Name{
@@ -206,7 +209,8 @@ a(b).$a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 													Name: "&b_5",
 													Type: Bound,
 												},
-												Next: &ElemNil{},
+												Position: Position{Line: 2, Column: 32},
+												Next:     &ElemNil{},
 											},
 											ProcessR: &ElemInput{
 												Channel: Name{
@@ -216,7 +220,8 @@ a(b).$a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 													Name: "&d_6",
 													Type: Bound,
 												},
-												Next: &ElemNil{},
+												Position: Position{Line: 2, Column: 41},
+												Next:     &ElemNil{},
 											},
 										},
 									},
@@ -0,0 +1,435 @@
+package pifra
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Equivalences by which an LTS can be minimised.
+const (
+	EquivStrong    = "strong"
+	EquivWeak      = "weak"
+	EquivBranching = "branching"
+)
+
+// minimiseLts returns the quotient of the LTS by strong, weak or branching
+// bisimilarity. Unexplored states are never merged.
+func minimiseLts(lts Lts, equiv string) (Lts, error) {
+	numStates := len(lts.States)
+	initial := getInitialPartition(lts)
+
+	var partition []int
+	switch equiv {
+	case EquivStrong:
+		partition = strongBisimulation(numStates, lts.Transitions, initial)
+	case EquivWeak:
+		partition = strongBisimulation(numStates, saturateTau(numStates, lts.Transitions), initial)
+	case EquivBranching:
+		partition = branchingBisimulation(numStates, lts.Transitions, initial)
+	default:
+		return Lts{}, fmt.Errorf("unknown equivalence %q: must be %s, %s or %s",
+			equiv, EquivStrong, EquivWeak, EquivBranching)
+	}
+	return getQuotientLts(lts, partition, equiv != EquivStrong), nil
+}
+
+// isExplored returns whether the transitions of a state were generated. States
+// are explored in the order of their IDs.
+func (lts Lts) isExplored(id int) bool {
+	return id < lts.StatesExplored && !lts.RegSizeReached[id]
+}
+
+// getInitialPartition places all explored states in one block, and every
+// other state in a block of its own.
+func getInitialPartition(lts Lts) []int {
+	partition := make([]int, len(lts.States))
+	block := 1
+	for id := range partition {
+		if !lts.isExplored(id) {
+			partition[id] = block
+			block++
+		}
+	}
+	return partition
+}
+
+type countKey struct {
+	State    int
+	Label    Label
+	Compound int
+}
+
+type incomingTransition struct {
+	Source int
+	Label  Label
+}
+
+// strongBisimulation returns the coarsest refinement of the initial partition
+// which is a strong bisimulation, by Paige–Tarjan partition refinement.
+func strongBisimulation(numStates int, trns []Transition, initial []int) []int {
+	incoming := make([][]incomingTransition, numStates)
+	for _, trn := range trns {
+		incoming[trn.Destination] = append(incoming[trn.Destination], incomingTransition{
+			Source: trn.Source,
+			Label:  trn.Label,
+		})
+	}
+
+	// Blocks of the partition P.
+	blockOf := make([]int, numStates)
+	var members [][]int
+	blockIds := make(map[int]int)
+	for id := 0; id < numStates; id++ {
+		b, ok := blockIds[initial[id]]
+		if !ok {
+			b = len(members)
+			blockIds[initial[id]] = b
+			members = append(members, nil)
+		}
+		blockOf[id] = b
+		members[b] = append(members[b], id)
+	}
+
+	// Compound blocks of the partition X, each the union of blocks of P.
+	var compoundOf []int
+	var compounds [][]int
+	var worklist []int
+	inWorklist := make(map[int]bool)
+	pushCompound := func(c int) {
+		if len(compounds[c]) > 1 && !inWorklist[c] {
+			inWorklist[c] = true
+			worklist = append(worklist, c)
+		}
+	}
+	compounds = append(compounds, nil)
+	for b := range members {
+		compoundOf = append(compoundOf, 0)
+		compounds[0] = append(compounds[0], b)
+	}
+
+	// Splits every block into the states in the set and the other states.
+	split := func(set map[int]bool) {
+		marked := make(map[int][]int)
+		var touched []int
+		for id := range set {
+			b := blockOf[id]
+			if _, ok := marked[b]; !ok {
+				touched = append(touched, b)
+			}
+			marked[b] = append(marked[b], id)
+		}
+		sort.Ints(touched)
+		for _, b := range touched {
+			if len(marked[b]) == len(members[b]) {
+				continue
+			}
+			newBlock := len(members)
+			sort.Ints(marked[b])
+			members = append(members, marked[b])
+			var rest []int
+			for _, id := range members[b] {
+				if !set[id] {
+					rest = append(rest, id)
+				}
+			}
+			members[b] = rest
+			for _, id := range marked[b] {
+				blockOf[id] = newBlock
+			}
+			c := compoundOf[b]
+			compoundOf = append(compoundOf, c)
+			compounds[c] = append(compounds[c], newBlock)
+			pushCompound(c)
+		}
+	}
+
+	// Counts of transitions into compound blocks.
+	counts := make(map[countKey]int)
+	labelPre := make(map[Label]map[int]bool)
+	var labels []Label
+	for _, trn := range trns {
+		counts[countKey{trn.Source, trn.Label, 0}]++
+		if _, ok := labelPre[trn.Label]; !ok {
+			labelPre[trn.Label] = make(map[int]bool)
+			labels = append(labels, trn.Label)
+		}
+		labelPre[trn.Label][trn.Source] = true
+	}
+	// Make P stable with respect to X.
+	for _, label := range labels {
+		split(labelPre[label])
+	}
+	pushCompound(0)
+
+	for len(worklist) > 0 {
+		s := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		inWorklist[s] = false
+		if len(compounds[s]) < 2 {
+			continue
+		}
+
+		// Remove the smaller of two blocks of S into a new compound block.
+		b := compounds[s][0]
+		if len(members[compounds[s][1]]) < len(members[b]) {
+			b = compounds[s][1]
+		}
+		var rest []int
+		for _, block := range compounds[s] {
+			if block != b {
+				rest = append(rest, block)
+			}
+		}
+		compounds[s] = rest
+		newCompound := len(compounds)
+		compounds = append(compounds, []int{b})
+		compoundOf[b] = newCompound
+		pushCompound(s)
+
+		// Transitions into B, by label.
+		preB := make(map[Label]map[int]int)
+		var preLabels []Label
+		for _, id := range members[b] {
+			for _, inc := range incoming[id] {
+				if _, ok := preB[inc.Label]; !ok {
+					preB[inc.Label] = make(map[int]int)
+					preLabels = append(preLabels, inc.Label)
+				}
+				preB[inc.Label][inc.Source]++
+			}
+		}
+
+		for _, label := range preLabels {
+			// Split with respect to B.
+			set := make(map[int]bool)
+			for id := range preB[label] {
+				set[id] = true
+			}
+			split(set)
+
+			// Split with respect to S \ B, i.e., states with transitions only
+			// into B and not into S \ B.
+			set = make(map[int]bool)
+			for id, count := range preB[label] {
+				if count == counts[countKey{id, label, s}] {
+					set[id] = true
+				}
+			}
+			split(set)
+
+			for id, count := range preB[label] {
+				key := countKey{id, label, s}
+				counts[key] = counts[key] - count
+				if counts[key] == 0 {
+					delete(counts, key)
+				}
+				counts[countKey{id, label, newCompound}] = count
+			}
+		}
+	}
+
+	return blockOf
+}
+
+// saturateTau returns the transitions of the LTS where a state can do an
+// action if it can do it preceded and followed by any number of τ-transitions.
+// Strong bisimilarity on the saturated LTS is weak bisimilarity on the LTS.
+func saturateTau(numStates int, trns []Transition) []Transition {
+	tauSuccs := make([][]int, numStates)
+	succs := make([][]Transition, numStates)
+	for _, trn := range trns {
+		if trn.Label.Symbol.Type == SymbolTypTau {
+			tauSuccs[trn.Source] = append(tauSuccs[trn.Source], trn.Destination)
+		} else {
+			succs[trn.Source] = append(succs[trn.Source], trn)
+		}
+	}
+
+	// τ-closure of each state, including itself.
+	closure := make([][]int, numStates)
+	for id := 0; id < numStates; id++ {
+		seen := map[int]bool{id: true}
+		stack := []int{id}
+		for len(stack) > 0 {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			closure[id] = append(closure[id], cur)
+			for _, dst := range tauSuccs[cur] {
+				if !seen[dst] {
+					seen[dst] = true
+					stack = append(stack, dst)
+				}
+			}
+		}
+		sort.Ints(closure[id])
+	}
+
+	tau := Label{
+		Symbol: Symbol{
+			Type: SymbolTypTau,
+		},
+	}
+	var saturated []Transition
+	seen := make(map[Transition]bool)
+	add := func(trn Transition) {
+		if !seen[trn] {
+			seen[trn] = true
+			saturated = append(saturated, trn)
+		}
+	}
+	for id := 0; id < numStates; id++ {
+		for _, mid := range closure[id] {
+			add(Transition{
+				Source:      id,
+				Destination: mid,
+				Label:       tau,
+			})
+			for _, trn := range succs[mid] {
+				for _, dst := range closure[trn.Destination] {
+					add(Transition{
+						Source:      id,
+						Destination: dst,
+						Label:       trn.Label,
+					})
+				}
+			}
+		}
+	}
+	return saturated
+}
+
+// branchingBisimulation returns the coarsest refinement of the initial
+// partition which is a branching bisimulation, by signature refinement. The
+// signature of a state is the set of its transitions which are not inert τ,
+// after any number of inert τ-transitions, paired with the block reached.
+func branchingBisimulation(numStates int, trns []Transition, initial []int) []int {
+	succs := make([][]Transition, numStates)
+	for _, trn := range trns {
+		succs[trn.Source] = append(succs[trn.Source], trn)
+	}
+
+	partition := append([]int{}, initial...)
+	numBlocks := -1
+	for {
+		sigs := make([]string, numStates)
+		for id := 0; id < numStates; id++ {
+			sig := make(map[string]bool)
+			seen := map[int]bool{id: true}
+			stack := []int{id}
+			for len(stack) > 0 {
+				cur := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, trn := range succs[cur] {
+					inert := trn.Label.Symbol.Type == SymbolTypTau &&
+						partition[trn.Destination] == partition[id]
+					if inert {
+						if !seen[trn.Destination] {
+							seen[trn.Destination] = true
+							stack = append(stack, trn.Destination)
+						}
+						continue
+					}
+					sig[PrettyPrintLabel(trn.Label)+strconv.Itoa(partition[trn.Destination])] = true
+				}
+			}
+			var sigStrs []string
+			for s := range sig {
+				sigStrs = append(sigStrs, s)
+			}
+			sort.Strings(sigStrs)
+			sigs[id] = strconv.Itoa(partition[id]) + ";" + strings.Join(sigStrs, ",")
+		}
+
+		blockIds := make(map[string]int)
+		newPartition := make([]int, numStates)
+		for id := 0; id < numStates; id++ {
+			b, ok := blockIds[sigs[id]]
+			if !ok {
+				b = len(blockIds)
+				blockIds[sigs[id]] = b
+			}
+			newPartition[id] = b
+		}
+		partition = newPartition
+		if len(blockIds) == numBlocks {
+			return partition
+		}
+		numBlocks = len(blockIds)
+	}
+}
+
+// getQuotientLts returns the LTS with a state for each block of the partition,
+// numbered in the order of their least states. Inert τ-transitions within a
+// block are removed if τ is internal.
+func getQuotientLts(lts Lts, partition []int, tauInternal bool) Lts {
+	blockIds := make(map[int]int)
+	blocks := make(map[int][]int)
+	states := make(map[int]Configuration)
+	regSizeReached := make(map[int]bool)
+	var divergent map[int]int
+	if lts.Divergent != nil {
+		divergent = make(map[int]int)
+	}
+	// Unexplored states are in blocks of their own, which are numbered after
+	// the blocks of the explored states, as are the states themselves.
+	statesExplored := 0
+	for id := 0; id < len(partition); id++ {
+		b, ok := blockIds[partition[id]]
+		if !ok {
+			b = len(blockIds)
+			blockIds[partition[id]] = b
+			states[b] = lts.States[id]
+			if id < lts.StatesExplored {
+				statesExplored++
+			}
+		}
+		blocks[b] = append(blocks[b], id)
+		if lts.RegSizeReached[id] {
+			regSizeReached[b] = true
+		}
+		if scc, ok := lts.Divergent[id]; ok {
+			divergent[b] = scc
+		}
+	}
+
+	var trns []Transition
+	trnsSeen := make(map[Transition]bool)
+	var provenance map[Transition][]Provenance
+	if lts.Provenance != nil {
+		provenance = make(map[Transition][]Provenance)
+	}
+	for _, trn := range lts.Transitions {
+		qtrn := Transition{
+			Source:      blockIds[partition[trn.Source]],
+			Destination: blockIds[partition[trn.Destination]],
+			Label:       trn.Label,
+		}
+		// A τ-cycle of a divergent component is kept as a τ-self-loop.
+		if tauInternal && qtrn.Label.Symbol.Type == SymbolTypTau && qtrn.Source == qtrn.Destination &&
+			!isDivergentTransition(lts, trn) {
+			continue
+		}
+		if !trnsSeen[qtrn] {
+			trnsSeen[qtrn] = true
+			trns = append(trns, qtrn)
+		}
+		// A transition of the quotient is derived as any of its transitions.
+		for _, p := range lts.Provenance[trn] {
+			addProvenance(provenance, qtrn, p)
+		}
+	}
+
+	quotient := lts
+	quotient.States = states
+	quotient.Transitions = trns
+	quotient.RegSizeReached = regSizeReached
+	quotient.StatesExplored = statesExplored
+	quotient.Blocks = blocks
+	quotient.Divergent = divergent
+	// The parents are of the states of the original LTS.
+	quotient.Parents = nil
+	quotient.Provenance = provenance
+	return quotient
+}
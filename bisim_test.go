@@ -0,0 +1,179 @@
+package pifra
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMinimise(t *testing.T) {
+	type states struct {
+		strong    int
+		weak      int
+		branching int
+	}
+	tests := map[string]struct {
+		input  []byte
+		states states
+	}{
+		"unfolding": {
+			input: []byte(`
+P(a) = a'<a>.a'<a>.P(a)
+a'<a>.P(a)
+`),
+			states: states{1, 1, 1},
+		},
+		"internal_choice": {
+			input: []byte(`
+a'<a>.0 + $c.(c'<c>.0 | c(x).a'<a>.0)
+`),
+			states: states{3, 2, 2},
+		},
+		"weak_not_branching": {
+			input: []byte(`
+d'<d>.a'<a>.($t.(t'<t>.0 | t(x).b'<b>.0) + c'<c>.0) +
+e'<e>.(a'<a>.($t.(t'<t>.0 | t(x).b'<b>.0) + c'<c>.0) + a'<a>.b'<b>.0)
+`),
+			states: states{6, 5, 6},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for equiv, want := range map[string]int{
+				EquivStrong:    test.states.strong,
+				EquivWeak:      test.states.weak,
+				EquivBranching: test.states.branching,
+			} {
+				minLts, err := minimiseLts(lts, equiv)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(minLts.States) != want {
+					t.Errorf("%s: got %d states, want %d", equiv, len(minLts.States), want)
+				}
+			}
+		})
+	}
+
+	if _, err := minimiseLts(Lts{}, "trace"); err == nil {
+		t.Error("expected error for unknown equivalence")
+	}
+}
+
+// TestMinimiseRefinement compares the Paige–Tarjan algorithm with naive
+// partition refinement, and checks that unexplored states are not merged.
+func TestMinimiseRefinement(t *testing.T) {
+	files, err := filepath.Glob("test/*.pi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxStatesExplored = 12
+	registerSize = 1073741824
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			input, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			numStates := len(lts.States)
+			initial := getInitialPartition(lts)
+
+			strong := strongBisimulation(numStates, lts.Transitions, initial)
+			if !samePartition(strong, naiveBisimulation(numStates, lts.Transitions, initial)) {
+				t.Error("strong bisimulation differs from naive refinement")
+			}
+			saturated := saturateTau(numStates, lts.Transitions)
+			weak := strongBisimulation(numStates, saturated, initial)
+			if !samePartition(weak, naiveBisimulation(numStates, saturated, initial)) {
+				t.Error("weak bisimulation differs from naive refinement")
+			}
+			branching := branchingBisimulation(numStates, lts.Transitions, initial)
+			if !refines(strong, branching) || !refines(branching, weak) {
+				t.Error("branching bisimulation is not between strong and weak")
+			}
+
+			minLts, err := minimiseLts(lts, EquivStrong)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for b, block := range minLts.Blocks {
+				explored := true
+				for _, id := range block {
+					if !lts.isExplored(id) && len(block) != 1 {
+						t.Errorf("unexplored state s%d merged into block %v", id, block)
+					}
+					explored = explored && lts.isExplored(id)
+				}
+				if minLts.isExplored(b) != explored {
+					t.Errorf("block s%d of %v is explored %t, want %t", b, block, minLts.isExplored(b), explored)
+				}
+			}
+		})
+	}
+}
+
+// naiveBisimulation refines the partition by the labels and destination
+// blocks of the transitions of each state until it is stable.
+func naiveBisimulation(numStates int, trns []Transition, initial []int) []int {
+	partition := append([]int{}, initial...)
+	for {
+		sigs := make([]map[string]bool, numStates)
+		for id := range sigs {
+			sigs[id] = map[string]bool{"": true}
+		}
+		for _, trn := range trns {
+			sigs[trn.Source][PrettyPrintLabel(trn.Label)+strconv.Itoa(partition[trn.Destination])] = true
+		}
+		var keys []map[string]bool
+		newPartition := make([]int, numStates)
+		for id := range newPartition {
+			sigs[id][strconv.Itoa(partition[id])+";"] = true
+			found := false
+			for b, key := range keys {
+				if reflect.DeepEqual(key, sigs[id]) {
+					newPartition[id] = b
+					found = true
+					break
+				}
+			}
+			if !found {
+				newPartition[id] = len(keys)
+				keys = append(keys, sigs[id])
+			}
+		}
+		if samePartition(partition, newPartition) {
+			return partition
+		}
+		partition = newPartition
+	}
+}
+
+// refines returns whether every block of p is contained in a block of q.
+func refines(p []int, q []int) bool {
+	blockOf := make(map[int]int)
+	for id, b := range p {
+		if qb, ok := blockOf[b]; ok && qb != q[id] {
+			return false
+		}
+		blockOf[b] = q[id]
+	}
+	return true
+}
+
+func samePartition(p []int, q []int) bool {
+	return refines(p, q) && refines(q, p)
+}
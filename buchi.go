@@ -0,0 +1,222 @@
+package pifra
+
+import (
+	"sort"
+)
+
+// buchiAutomaton is a generalised Büchi automaton over labels. Each state
+// constrains the label of the transition taken at its position by literals,
+// and an accepting run visits each acceptance set infinitely often.
+type buchiAutomaton struct {
+	Literals   [][]ltlFormula
+	Initial    []int
+	Successors [][]int
+	Accepting  [][]bool
+}
+
+// buchiNode is a node of the tableau construction: the formulas still to be
+// processed, the formulas which hold at the position of the node, and the
+// formulas which must hold at the next position.
+type buchiNode struct {
+	Incoming map[int]bool
+	New      map[string]ltlFormula
+	Old      map[string]ltlFormula
+	Next     map[string]ltlFormula
+}
+
+// buchiInit is the incoming node of the initial states.
+const buchiInit = -1
+
+// newBuchiAutomaton translates a formula in negation normal form into a
+// generalised Büchi automaton, by the construction of Gerth et al.
+func newBuchiAutomaton(formula ltlFormula) buchiAutomaton {
+	var nodes []*buchiNode
+	var expand func(node *buchiNode)
+	expand = func(node *buchiNode) {
+		if len(node.New) == 0 {
+			for _, other := range nodes {
+				if sameLtlSet(other.Old, node.Old) && sameLtlSet(other.Next, node.Next) {
+					for in := range node.Incoming {
+						other.Incoming[in] = true
+					}
+					return
+				}
+			}
+			nodes = append(nodes, node)
+			expand(&buchiNode{
+				Incoming: map[int]bool{len(nodes) - 1: true},
+				New:      copyLtlSet(node.Next),
+				Old:      make(map[string]ltlFormula),
+				Next:     make(map[string]ltlFormula),
+			})
+			return
+		}
+
+		// Process the formulas in a fixed order.
+		var keys []string
+		for key := range node.New {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		key := keys[0]
+		eta := node.New[key]
+		delete(node.New, key)
+		if _, ok := node.Old[key]; ok {
+			expand(node)
+			return
+		}
+
+		switch eta.Type {
+		case ltlFalse:
+			return
+		case ltlTrue:
+			node.Old[key] = eta
+			expand(node)
+		case ltlProp, ltlNotProp:
+			negated := eta
+			negated.Type = ltlNotProp
+			if eta.Type == ltlNotProp {
+				negated.Type = ltlProp
+			}
+			if _, ok := node.Old[getLtlKey(negated)]; ok {
+				return
+			}
+			node.Old[key] = eta
+			expand(node)
+		case ltlAnd:
+			node.Old[key] = eta
+			for _, sub := range eta.Subs {
+				if _, ok := node.Old[getLtlKey(sub)]; !ok {
+					node.New[getLtlKey(sub)] = sub
+				}
+			}
+			expand(node)
+		case ltlNext:
+			node.Old[key] = eta
+			node.Next[getLtlKey(eta.Subs[0])] = eta.Subs[0]
+			expand(node)
+		case ltlOr, ltlUntil, ltlRelease:
+			// a | b, a U b = b | (a & X(a U b)), and a R b = (a & b) | (b & X(a R b)).
+			a, b := eta.Subs[0], eta.Subs[1]
+			var new1, new2, next1 []ltlFormula
+			switch eta.Type {
+			case ltlOr:
+				new1, new2 = []ltlFormula{a}, []ltlFormula{b}
+			case ltlUntil:
+				new1, new2, next1 = []ltlFormula{a}, []ltlFormula{b}, []ltlFormula{eta}
+			case ltlRelease:
+				new1, new2, next1 = []ltlFormula{b}, []ltlFormula{a, b}, []ltlFormula{eta}
+			}
+			node1 := &buchiNode{
+				Incoming: copyIncoming(node.Incoming),
+				New:      copyLtlSet(node.New),
+				Old:      copyLtlSet(node.Old),
+				Next:     copyLtlSet(node.Next),
+			}
+			node1.Old[key] = eta
+			for _, f := range new1 {
+				if _, ok := node1.Old[getLtlKey(f)]; !ok {
+					node1.New[getLtlKey(f)] = f
+				}
+			}
+			for _, f := range next1 {
+				node1.Next[getLtlKey(f)] = f
+			}
+			node2 := node
+			node2.Old[key] = eta
+			for _, f := range new2 {
+				if _, ok := node2.Old[getLtlKey(f)]; !ok {
+					node2.New[getLtlKey(f)] = f
+				}
+			}
+			expand(node1)
+			expand(node2)
+		}
+	}
+	expand(&buchiNode{
+		Incoming: map[int]bool{buchiInit: true},
+		New:      map[string]ltlFormula{getLtlKey(formula): formula},
+		Old:      make(map[string]ltlFormula),
+		Next:     make(map[string]ltlFormula),
+	})
+
+	var untils []ltlFormula
+	var collect func(f ltlFormula)
+	seen := make(map[string]bool)
+	collect = func(f ltlFormula) {
+		if f.Type == ltlUntil && !seen[getLtlKey(f)] {
+			seen[getLtlKey(f)] = true
+			untils = append(untils, f)
+		}
+		for _, sub := range f.Subs {
+			collect(sub)
+		}
+	}
+	collect(formula)
+
+	automaton := buchiAutomaton{
+		Literals:   make([][]ltlFormula, len(nodes)),
+		Successors: make([][]int, len(nodes)),
+		Accepting:  make([][]bool, len(untils)),
+	}
+	for i := range automaton.Accepting {
+		automaton.Accepting[i] = make([]bool, len(nodes))
+	}
+	for id, node := range nodes {
+		for in := range node.Incoming {
+			if in == buchiInit {
+				automaton.Initial = append(automaton.Initial, id)
+			} else {
+				automaton.Successors[in] = append(automaton.Successors[in], id)
+			}
+		}
+		var keys []string
+		for key := range node.Old {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if f := node.Old[key]; f.Type == ltlProp || f.Type == ltlNotProp {
+				automaton.Literals[id] = append(automaton.Literals[id], f)
+			}
+		}
+		for i, until := range untils {
+			_, required := node.Old[getLtlKey(until)]
+			_, fulfilled := node.Old[getLtlKey(until.Subs[1])]
+			automaton.Accepting[i][id] = !required || fulfilled
+		}
+	}
+	sort.Ints(automaton.Initial)
+	for id := range automaton.Successors {
+		sort.Ints(automaton.Successors[id])
+	}
+	return automaton
+}
+
+func copyLtlSet(set map[string]ltlFormula) map[string]ltlFormula {
+	cp := make(map[string]ltlFormula)
+	for key, f := range set {
+		cp[key] = f
+	}
+	return cp
+}
+
+func copyIncoming(incoming map[int]bool) map[int]bool {
+	cp := make(map[int]bool)
+	for id := range incoming {
+		cp[id] = true
+	}
+	return cp
+}
+
+func sameLtlSet(a map[string]ltlFormula, b map[string]ltlFormula) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
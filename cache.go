@@ -0,0 +1,265 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+)
+
+// transCache memoises the transitions of parallel components. It is keyed by
+// the normalised sub-configuration, and is only active during exploration
+// (nil otherwise).
+var transCache map[string]transCacheEntry
+
+type transCacheEntry struct {
+	Confs []Configuration
+	// The register of the sub-configuration the transitions were computed
+	// from, whose names are renamed for a sub-configuration of the same key.
+	Registers map[int]string
+	// Whether the computation cleared the visited processes of REC.
+	ClearsRecVisited bool
+}
+
+var transCacheHits int
+var transCacheMisses int
+
+var disableTransCache bool
+
+func initTransCache() {
+	transCache = nil
+	if !disableTransCache {
+		transCache = make(map[string]transCacheEntry)
+	}
+	transCacheHits = 0
+	transCacheMisses = 0
+}
+
+// getTransCacheKey returns the cache key of a sub-configuration: its process
+// with the names of the register written as their labels, the labels, the
+// processes visited by REC, and the prefix positions and components when
+// explaining.
+func getTransCacheKey(conf Configuration) string {
+	var procs []string
+	for proc := range recVisitedProcs {
+		procs = append(procs, proc)
+	}
+	sort.Strings(procs)
+
+	names := make(map[string]string)
+	var labels []string
+	for _, label := range conf.Registers.Labels() {
+		names[conf.Registers.Registers[label]] = "@" + strconv.Itoa(label)
+		labels = append(labels, strconv.Itoa(label))
+	}
+	proc := deepcopy.Copy(conf.Process).(Element)
+	renameNames(proc, names)
+
+	key := strings.Join(procs, ",") + ";" + strings.Join(labels, ",") + ";" + PrettyPrintAst(proc)
+	if explainTransitions {
+		key = key + ";" + getPrefixPositionsKey(conf.Process) + ";" + getComponentKey(conf.Process)
+	}
+	return key
+}
+
+// transComponent returns the transitions of a parallel component, reusing
+// previously computed transitions of components of the same key if possible.
+func transComponent(conf Configuration) []Configuration {
+	if transCache == nil || hasDuplicateNames(conf.Registers) {
+		return trans(conf)
+	}
+
+	key := getTransCacheKey(conf)
+	if entry, ok := transCache[key]; ok {
+		transCacheHits++
+		if entry.ClearsRecVisited {
+			recVisitedProcs = nil
+		}
+		confs := deepcopy.Copy(entry.Confs).([]Configuration)
+		renameRegisterNames(confs, entry.Registers, conf.Registers.Registers)
+		return refreshComponents(conf, refreshGeneratedNames(conf, confs))
+	}
+	transCacheMisses++
+
+	registers := deepcopy.Copy(conf.Registers.Registers).(map[int]string)
+	confs := trans(conf)
+	transCache[key] = transCacheEntry{
+		Confs:            deepcopy.Copy(confs).([]Configuration),
+		Registers:        registers,
+		ClearsRecVisited: recVisitedProcs == nil,
+	}
+	return confs
+}
+
+// hasDuplicateNames returns whether a name is at more than one label of a
+// register, so that the register cannot be renamed by its labels.
+func hasDuplicateNames(reg Registers) bool {
+	seen := make(map[string]bool)
+	for _, name := range reg.Registers {
+		if seen[name] {
+			return true
+		}
+		seen[name] = true
+	}
+	return false
+}
+
+// renameRegisterNames renames the names of a register in the processes and
+// registers of cached transitions to the names at the same labels of another
+// register.
+func renameRegisterNames(confs []Configuration, from map[int]string, to map[int]string) {
+	names := make(map[string]string)
+	for label, name := range from {
+		if name != to[label] {
+			names[name] = to[label]
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	for i := range confs {
+		renameNames(confs[i].Process, names)
+		for label, name := range confs[i].Registers.Registers {
+			if newName, ok := names[name]; ok {
+				confs[i].Registers.Registers[label] = newName
+			}
+		}
+	}
+}
+
+// refreshGeneratedNames renames the names generated by alpha-conversion during
+// the computation of cached transitions, so that reused transitions do not
+// share names with each other.
+func refreshGeneratedNames(conf Configuration, confs []Configuration) []Configuration {
+	oldNames := make(map[string]bool)
+	for _, name := range getAllNames(conf.Process) {
+		oldNames[name] = true
+	}
+	for _, name := range conf.Registers.Registers {
+		oldNames[name] = true
+	}
+
+	newNames := make(map[string]string)
+	genName := func(name string) string {
+		if newName, ok := newNames[name]; ok {
+			return newName
+		}
+		prefix := strings.TrimPrefix(name, bnPrefix)
+		if i := strings.LastIndex(prefix, "_"); i != -1 {
+			prefix = prefix[:i]
+		}
+		newName := generateBoundName(prefix)
+		newNames[name] = newName
+		return newName
+	}
+
+	for i, c := range confs {
+		names := make(map[string]string)
+		for _, name := range getAllNames(c.Process) {
+			if !oldNames[name] && strings.HasPrefix(name, bnPrefix) {
+				names[name] = genName(name)
+			}
+		}
+		renameNames(c.Process, names)
+		for label, name := range c.Registers.Registers {
+			if !oldNames[name] && strings.HasPrefix(name, bnPrefix) {
+				confs[i].Registers.Registers[label] = genName(name)
+			}
+		}
+	}
+	return confs
+}
+
+// getAllNames returns all names in the AST, including binders.
+func getAllNames(elem Element) []string {
+	var names []string
+	var getAllNamesAcc func(Element)
+	getAllNamesAcc = func(elem Element) {
+		switch elem.Type() {
+		case ElemTypNil:
+		case ElemTypOutput:
+			outElem := elem.(*ElemOutput)
+			names = append(names, outElem.Channel.Name, outElem.Output.Name)
+			getAllNamesAcc(outElem.Next)
+		case ElemTypInput:
+			inpElem := elem.(*ElemInput)
+			names = append(names, inpElem.Channel.Name, inpElem.Input.Name)
+			getAllNamesAcc(inpElem.Next)
+		case ElemTypMatch:
+			matchElem := elem.(*ElemEquality)
+			names = append(names, matchElem.NameL.Name, matchElem.NameR.Name)
+			getAllNamesAcc(matchElem.Next)
+		case ElemTypRestriction:
+			resElem := elem.(*ElemRestriction)
+			names = append(names, resElem.Restrict.Name)
+			getAllNamesAcc(resElem.Next)
+		case ElemTypSum:
+			sumElem := elem.(*ElemSum)
+			getAllNamesAcc(sumElem.ProcessL)
+			getAllNamesAcc(sumElem.ProcessR)
+		case ElemTypParallel:
+			parElem := elem.(*ElemParallel)
+			getAllNamesAcc(parElem.ProcessL)
+			getAllNamesAcc(parElem.ProcessR)
+		case ElemTypProcess:
+			procElem := elem.(*ElemProcess)
+			for _, param := range procElem.Parameters {
+				names = append(names, param.Name)
+			}
+		case ElemTypRoot:
+			rootElem := elem.(*ElemRoot)
+			getAllNamesAcc(rootElem.Next)
+		}
+	}
+	getAllNamesAcc(elem)
+	return names
+}
+
+// renameNames renames every occurrence of the names of a map in the AST at
+// once, including binders, retaining the name types.
+func renameNames(elem Element, names map[string]string) {
+	rename := func(name *Name) {
+		if newName, ok := names[name.Name]; ok {
+			name.Name = newName
+		}
+	}
+	switch elem.Type() {
+	case ElemTypNil:
+	case ElemTypOutput:
+		outElem := elem.(*ElemOutput)
+		rename(&outElem.Channel)
+		rename(&outElem.Output)
+		renameNames(outElem.Next, names)
+	case ElemTypInput:
+		inpElem := elem.(*ElemInput)
+		rename(&inpElem.Channel)
+		rename(&inpElem.Input)
+		renameNames(inpElem.Next, names)
+	case ElemTypMatch:
+		matchElem := elem.(*ElemEquality)
+		rename(&matchElem.NameL)
+		rename(&matchElem.NameR)
+		renameNames(matchElem.Next, names)
+	case ElemTypRestriction:
+		resElem := elem.(*ElemRestriction)
+		rename(&resElem.Restrict)
+		renameNames(resElem.Next, names)
+	case ElemTypSum:
+		sumElem := elem.(*ElemSum)
+		renameNames(sumElem.ProcessL, names)
+		renameNames(sumElem.ProcessR, names)
+	case ElemTypParallel:
+		parElem := elem.(*ElemParallel)
+		renameNames(parElem.ProcessL, names)
+		renameNames(parElem.ProcessR, names)
+	case ElemTypProcess:
+		procElem := elem.(*ElemProcess)
+		for i := range procElem.Parameters {
+			rename(&procElem.Parameters[i])
+		}
+	case ElemTypRoot:
+		rootElem := elem.(*ElemRoot)
+		renameNames(rootElem.Next, names)
+	}
+}
@@ -0,0 +1,76 @@
+package pifra
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTransCache(t *testing.T) {
+	// The bounds of the expected outputs of TestLtsGeneration.
+	maxStates := map[string]int{
+		"gen-fresh-b.pi": 100,
+	}
+	registerSize = 1073741824
+	defer func() {
+		disableTransCache = false
+	}()
+
+	testFiles, err := filepath.Glob(filepath.Join("test", "*.pi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, testFile := range testFiles {
+		t.Run(filepath.Base(testFile), func(t *testing.T) {
+			input, err := ioutil.ReadFile(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			maxStatesExplored = 10
+			if bound, ok := maxStates[filepath.Base(testFile)]; ok {
+				maxStatesExplored = bound
+			}
+
+			disableTransCache = true
+			lts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			disableTransCache = false
+			cachedLts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(generatePrettyLts(lts), generatePrettyLts(cachedLts)) {
+				t.Errorf("cached LTS differs from uncached LTS:\n%s", generatePrettyLts(cachedLts))
+			}
+		})
+	}
+}
+
+func TestTransCacheKey(t *testing.T) {
+	explainTransitions = false
+	recVisitedProcs = nil
+	conf := func(reg map[int]string, proc string) Configuration {
+		elem, err := InitProgram([]byte(proc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Configuration{
+			Process:   elem,
+			Registers: Registers{Size: len(reg), Registers: reg},
+		}
+	}
+
+	left := getTransCacheKey(conf(map[int]string{1: "a", 2: "b"}, "a'<b>.0"))
+	right := getTransCacheKey(conf(map[int]string{1: "b", 2: "a"}, "b'<a>.0"))
+	if left != right {
+		t.Errorf("keys of components equal up to the labels of their names differ: %s and %s", left, right)
+	}
+	other := getTransCacheKey(conf(map[int]string{1: "a", 2: "b"}, "b'<a>.0"))
+	if left == other {
+		t.Errorf("keys of components with different labels are equal: %s", left)
+	}
+}
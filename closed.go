@@ -0,0 +1,27 @@
+package pifra
+
+import (
+	"strings"
+)
+
+// closedSystem restricts the exploration to τ-transitions, i.e., the
+// communications (COMM and CLOSE) between the components of a whole system,
+// without the inputs and outputs of the environment.
+var closedSystem bool
+
+// observeMarked also explores the outputs on marked names in a closed system,
+// as events observable by the environment.
+var observeMarked bool
+
+// filterClosedConfs returns the configurations reached by τ-transitions, and
+// by outputs on marked names if they are observed.
+func filterClosedConfs(confs []Configuration) []Configuration {
+	var closedConfs []Configuration
+	for _, conf := range confs {
+		if isTauConf(conf) || (observeMarked && conf.Label.Symbol.Type == SymbolTypOutput &&
+			strings.HasPrefix(conf.Registers.GetName(conf.Label.Symbol.Value), "_")) {
+			closedConfs = append(closedConfs, conf)
+		}
+	}
+	return closedConfs
+}
@@ -0,0 +1,74 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClosedSystem(t *testing.T) {
+	tests := map[string]struct {
+		input         []byte
+		observeMarked bool
+		partialOrder  bool
+		states        int
+		labels        []string
+	}{
+		"communication": {
+			input:  []byte(`a'<a>.0 | a(x).b'<x>.0`),
+			states: 2,
+			labels: []string{"t   "},
+		},
+		"restricted_communication": {
+			input:  []byte(`$c.(a'<c>.0 | a(x).x'<x>.0 | c(y).0)`),
+			states: 3,
+			labels: []string{"t   ", "t   "},
+		},
+		"marked_outputs_hidden": {
+			input:  []byte(`a'<a>.0 | a(x)._BAD'<x>.0`),
+			states: 2,
+			labels: []string{"t   "},
+		},
+		"marked_outputs_observed": {
+			input:         []byte(`a'<a>.0 | a(x)._BAD'<x>.b'<b>.0`),
+			observeMarked: true,
+			states:        3,
+			labels:        []string{"t   ", "1'2 "},
+		},
+		"marked_outputs_observed_with_por": {
+			input:         []byte(`$c.(c'<c>.0 | c(x).0) | _BAD'<_BAD>.0`),
+			observeMarked: true,
+			partialOrder:  true,
+			states:        3,
+			labels:        []string{"t   ", "1'1 "},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		closedSystem = false
+		observeMarked = false
+		partialOrderReduction = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			closedSystem = true
+			observeMarked = test.observeMarked
+			partialOrderReduction = test.partialOrder
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lts.States) != test.states {
+				t.Errorf("got %d states, want %d", len(lts.States), test.states)
+			}
+			var labels []string
+			for _, trn := range lts.Transitions {
+				labels = append(labels, PrettyPrintLabel(trn.Label))
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("got labels %q, want %q", labels, test.labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package pifra
+
+import (
+	"strconv"
+	"strings"
+)
+
+// componentIndex numbers the unfoldings of process definitions, which are the
+// instances of the components tagging their prefixes.
+var componentIndex int
+
+// generateComponent returns the tag of a new instance of a process
+// definition, e.g., Server#3.
+func generateComponent(name string) string {
+	componentIndex = componentIndex + 1
+	return name + "#" + strconv.Itoa(componentIndex)
+}
+
+// getComponentDefinition returns the process definition of the instance of a
+// component tag.
+func getComponentDefinition(component string) string {
+	if i := strings.LastIndex(component, "#"); i != -1 {
+		return component[:i]
+	}
+	return component
+}
+
+// tagComponent tags the prefixes of a process unfolded from a process
+// definition with the instance of the definition, except those of the
+// processes it calls, which are tagged when they are unfolded.
+func tagComponent(elem Element, instance string) {
+	walkPrefixes(elem, func(position Position, component *string) {
+		*component = instance
+	})
+}
+
+// getComponentKey returns the component tags of the prefixes of a process,
+// which order otherwise equal parallel components and summands. The prefixes
+// are only tagged when explaining transitions.
+func getComponentKey(elem Element) string {
+	if !explainTransitions {
+		return ""
+	}
+	var tags []string
+	walkPrefixes(elem, func(position Position, component *string) {
+		tags = append(tags, *component)
+	})
+	return strings.Join(tags, ",")
+}
+
+// refreshComponents tags the instances unfolded by cached transitions of a
+// configuration as new instances, so that they are distinct from those of
+// the other transitions reusing them.
+func refreshComponents(conf Configuration, confs []Configuration) []Configuration {
+	if !explainTransitions {
+		return confs
+	}
+	oldComponents := make(map[string]bool)
+	walkPrefixes(conf.Process, func(position Position, component *string) {
+		oldComponents[*component] = true
+	})
+
+	newComponents := make(map[string]string)
+	refresh := func(component string) string {
+		if component == "" || oldComponents[component] {
+			return component
+		}
+		if _, ok := newComponents[component]; !ok {
+			newComponents[component] = generateComponent(getComponentDefinition(component))
+		}
+		return newComponents[component]
+	}
+	for i := range confs {
+		walkPrefixes(confs[i].Process, func(position Position, component *string) {
+			*component = refresh(*component)
+		})
+		confs[i].Provenance = refreshProvenanceComponents(confs[i].Provenance, refresh)
+	}
+	return confs
+}
+
+// refreshProvenanceComponents returns a derivation with the components of its
+// axioms refreshed.
+func refreshProvenanceComponents(p Provenance, refresh func(string) string) Provenance {
+	p.Component = refresh(p.Component)
+	if len(p.Premises) > 0 {
+		premises := make([]Provenance, len(p.Premises))
+		for i, premise := range p.Premises {
+			premises[i] = refreshProvenanceComponents(premise, refresh)
+		}
+		p.Premises = premises
+	}
+	return p
+}
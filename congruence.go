@@ -3,6 +3,7 @@ package pifra
 import (
 	"sort"
 	"strconv"
+	"strings"
 )
 
 var bnPrefix = "&"
@@ -88,6 +89,9 @@ func normaliseBoundNames(conf Configuration) {
 			return newName
 		}
 		newName := bnPrefix + strconv.Itoa(bni)
+		if strings.Contains(oldName, secretTag) {
+			newName = newName + secretTag
+		}
 		bni = bni + 1
 		oldNames[oldName] = newName
 		return newName
@@ -501,16 +505,22 @@ func sortSumPar(elem Element) Element {
 		}
 		procs := []struct {
 			Rank    string
+			Tags    string
 			Process Element
 		}{}
 		for _, child := range sumChildren {
 			procs = append(procs, struct {
 				Rank    string
+				Tags    string
 				Process Element
-			}{PrettyPrintAst(child), child})
+			}{PrettyPrintAst(child), getComponentKey(child), child})
 		}
+		// Equal processes are ordered by their component tags.
 		sort.Slice(procs, func(i, j int) bool {
-			return procs[i].Rank < procs[j].Rank
+			if procs[i].Rank != procs[j].Rank {
+				return procs[i].Rank < procs[j].Rank
+			}
+			return procs[i].Tags < procs[j].Tags
 		})
 		head := &ElemSum{
 			ProcessL: procs[0].Process,
@@ -534,16 +544,22 @@ func sortSumPar(elem Element) Element {
 		// Size of procs is minimum of 2.
 		procs := []struct {
 			Rank    string
+			Tags    string
 			Process Element
 		}{}
 		for _, child := range parChildren {
 			procs = append(procs, struct {
 				Rank    string
+				Tags    string
 				Process Element
-			}{PrettyPrintAst(child), child})
+			}{PrettyPrintAst(child), getComponentKey(child), child})
 		}
+		// Equal processes are ordered by their component tags.
 		sort.Slice(procs, func(i, j int) bool {
-			return procs[i].Rank < procs[j].Rank
+			if procs[i].Rank != procs[j].Rank {
+				return procs[i].Rank < procs[j].Rank
+			}
+			return procs[i].Tags < procs[j].Tags
 		})
 		head := &ElemParallel{
 			ProcessL: procs[0].Process,
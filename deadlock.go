@@ -0,0 +1,131 @@
+package pifra
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// isTerminated returns whether the process of a configuration is 0, up to
+// restrictions.
+func isTerminated(conf Configuration) bool {
+	_, comps := getComponents(conf.Process)
+	for _, comp := range comps {
+		if comp.Type() != ElemTypNil {
+			return false
+		}
+	}
+	return true
+}
+
+// getDeadlocks returns the explored states without transitions, split into
+// states which terminated successfully, i.e., whose process is 0, and states
+// which are deadlocked, i.e., whose components are blocked, in ID order.
+// States which were not explored may have transitions, so are excluded.
+func getDeadlocks(lts Lts) ([]int, []int) {
+	hasTrns := make(map[int]bool)
+	for _, trn := range lts.Transitions {
+		hasTrns[trn.Source] = true
+	}
+
+	var deadlocks []int
+	var terminations []int
+	for id := 0; id < len(lts.States); id++ {
+		if !lts.isExplored(id) || hasTrns[id] {
+			continue
+		}
+		if isTerminated(lts.States[id]) {
+			terminations = append(terminations, id)
+		} else {
+			deadlocks = append(deadlocks, id)
+		}
+	}
+	return deadlocks, terminations
+}
+
+// getShortestPaths returns the transition by which each state is first
+// reached in a breadth-first traversal of the LTS from the root, which are the
+// parents recorded by the exploration, if any.
+func getShortestPaths(lts Lts) map[int]Transition {
+	if lts.Parents != nil {
+		return lts.Parents
+	}
+	trns := getTransitionsBySource(lts.Transitions)
+	parents := make(map[int]Transition)
+	visited := map[int]bool{0: true}
+	queue := []int{0}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, trn := range trns[id] {
+			if !visited[trn.Destination] {
+				visited[trn.Destination] = true
+				parents[trn.Destination] = trn
+				queue = append(queue, trn.Destination)
+			}
+		}
+	}
+	return parents
+}
+
+// getPath returns the transitions from the root to a state by the parent
+// transitions of getShortestPaths.
+func getPath(parents map[int]Transition, id int) []Transition {
+	var path []Transition
+	for id != 0 {
+		trn, ok := parents[id]
+		if !ok {
+			return nil
+		}
+		path = append([]Transition{trn}, path...)
+		id = trn.Source
+	}
+	return path
+}
+
+// prettyPrintPath returns the transitions of a path with a line per
+// transition, in the format of the pretty-printed LTS.
+func prettyPrintPath(path []Transition) string {
+	var buffer bytes.Buffer
+	for i, trn := range path {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString("s" + strconv.Itoa(trn.Source) + "  " + PrettyPrintLabel(trn.Label) +
+			"  s" + strconv.Itoa(trn.Destination))
+	}
+	return buffer.String()
+}
+
+// generateDeadlockReport returns the terminated and deadlocked states of the
+// LTS, with a shortest trace from the root to each deadlock.
+func generateDeadlockReport(lts Lts) []byte {
+	deadlocks, terminations := getDeadlocks(lts)
+	parents := getShortestPaths(lts)
+
+	var unexplored int
+	for id := range lts.States {
+		if !lts.isExplored(id) {
+			unexplored++
+		}
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("deadlocks            " + strconv.Itoa(len(deadlocks)) + "\n")
+	buffer.WriteString("terminations         " + strconv.Itoa(len(terminations)) + "\n")
+	buffer.WriteString("states unexplored    " + strconv.Itoa(unexplored))
+	if len(terminations) != 0 {
+		buffer.WriteString("\n\nterminated")
+		for _, id := range terminations {
+			buffer.WriteString(" s" + strconv.Itoa(id))
+		}
+	}
+	for _, id := range deadlocks {
+		conf := lts.States[id]
+		buffer.WriteString("\n\ndeadlock s" + strconv.Itoa(id) + " = " +
+			PrettyPrintRegister(conf.Registers) + " |- " + PrettyPrintAst(conf.Process))
+		if path := getPath(parents, id); len(path) != 0 {
+			buffer.WriteString("\n" + prettyPrintPath(path))
+		}
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,81 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDeadlocks(t *testing.T) {
+	tests := map[string]struct {
+		input        []byte
+		deadlocks    []string
+		terminations int
+		pathLens     []int
+	}{
+		"termination": {
+			input:        []byte(`a'<a>.0 | b'<b>.0`),
+			terminations: 1,
+		},
+		"blocked_on_restricted_channel": {
+			input:     []byte(`a'<a>.$x.(x(y).0 | 0)`),
+			deadlocks: []string{"$&1.&1(&2).0"},
+			pathLens:  []int{1},
+		},
+		"deadlock_and_termination": {
+			input:        []byte(`a(x).($y.(y(z).0 | x'<x>.0) + x'<a>.0)`),
+			deadlocks:    []string{"$&1.&1(&2).0"},
+			terminations: 1,
+			pathLens:     []int{2},
+		},
+		"false_match": {
+			input:     []byte(`a'<a>.[a=b]a'<a>.0`),
+			deadlocks: []string{"[#1=#2]#1'<#1>.0"},
+			pathLens:  []int{1},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deadlocks, terminations := getDeadlocks(lts)
+			parents := getShortestPaths(lts)
+			var procs []string
+			var pathLens []int
+			for _, id := range deadlocks {
+				procs = append(procs, PrettyPrintAst(lts.States[id].Process))
+				path := getPath(parents, id)
+				pathLens = append(pathLens, len(path))
+				if len(path) != 0 && (path[0].Source != 0 || path[len(path)-1].Destination != id) {
+					t.Errorf("path to s%d does not lead from s0", id)
+				}
+			}
+			if !reflect.DeepEqual(procs, test.deadlocks) {
+				t.Errorf("got deadlocks %v, want %v", procs, test.deadlocks)
+			}
+			if !reflect.DeepEqual(pathLens, test.pathLens) {
+				t.Errorf("got path lengths %v, want %v", pathLens, test.pathLens)
+			}
+			if len(terminations) != test.terminations {
+				t.Errorf("got %d terminations, want %d", len(terminations), test.terminations)
+			}
+		})
+	}
+}
+
+func TestGetDeadlocksUnexplored(t *testing.T) {
+	maxStatesExplored = 1
+	registerSize = 1073741824
+	lts, err := generateLts([]byte(`a'<a>.b'<b>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadlocks, terminations := getDeadlocks(lts)
+	if len(deadlocks) != 0 || len(terminations) != 0 {
+		t.Errorf("unexplored state reported: deadlocks %v, terminations %v", deadlocks, terminations)
+	}
+}
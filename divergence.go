@@ -0,0 +1,99 @@
+package pifra
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+)
+
+// getDivergences returns the strongly connected components of the LTS
+// restricted to τ-transitions which contain a τ-cycle, i.e., in which the
+// process can loop forever without interacting. Each component is sorted, and
+// the components are ordered by their least state.
+func getDivergences(lts Lts) [][]int {
+	tauSuccs := make(map[int][]int)
+	for _, trn := range lts.Transitions {
+		if trn.Label.Symbol.Type == SymbolTypTau {
+			tauSuccs[trn.Source] = append(tauSuccs[trn.Source], trn.Destination)
+		}
+	}
+	var ids []int
+	for id := range lts.States {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var divergences [][]int
+	for _, scc := range getStronglyConnectedComponents(ids, func(id int) []int {
+		return tauSuccs[id]
+	}) {
+		divergent := len(scc) > 1
+		for _, succ := range tauSuccs[scc[0]] {
+			divergent = divergent || succ == scc[0]
+		}
+		if divergent {
+			sort.Ints(scc)
+			divergences = append(divergences, scc)
+		}
+	}
+	sort.Slice(divergences, func(i, j int) bool {
+		return divergences[i][0] < divergences[j][0]
+	})
+	return divergences
+}
+
+// getDivergentStates returns the index of the divergent component of each
+// state in one.
+func getDivergentStates(divergences [][]int) map[int]int {
+	divergent := make(map[int]int)
+	for i, scc := range divergences {
+		for _, id := range scc {
+			divergent[id] = i
+		}
+	}
+	return divergent
+}
+
+// isDivergentTransition returns whether a transition is a τ-transition within
+// a divergent component, highlighted in the Graphviz output.
+func isDivergentTransition(lts Lts, trn Transition) bool {
+	if lts.Divergent == nil || trn.Label.Symbol.Type != SymbolTypTau {
+		return false
+	}
+	src, ok := lts.Divergent[trn.Source]
+	dst, okDst := lts.Divergent[trn.Destination]
+	return ok && okDst && src == dst
+}
+
+// generateDivergenceReport returns the divergent components of the LTS, with
+// a shortest trace from the root to each.
+func generateDivergenceReport(lts Lts) []byte {
+	divergences := getDivergences(lts)
+	parents := getShortestPaths(lts)
+
+	var unexplored int
+	for id := range lts.States {
+		if !lts.isExplored(id) {
+			unexplored++
+		}
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("divergences          " + strconv.Itoa(len(divergences)) + "\n")
+	buffer.WriteString("states unexplored    " + strconv.Itoa(unexplored))
+	for _, scc := range divergences {
+		buffer.WriteString("\n\ndivergent " + prettyPrintBlock(scc))
+		// The trace to the state of the component reached first.
+		var path []Transition
+		for i, id := range scc {
+			p := getPath(parents, id)
+			if i == 0 || len(p) < len(path) {
+				path = p
+			}
+		}
+		if len(path) != 0 {
+			buffer.WriteString("\n" + prettyPrintPath(path))
+		}
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,99 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDivergences(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		sizes    []int
+		pathLens []int
+	}{
+		"no_cycle": {
+			input: []byte(`$x.(x'<x>.0 | x(y).0)`),
+		},
+		"visible_cycle": {
+			input: []byte(`P(a) = a'<a>.P(a)
+P(a)`),
+		},
+		"tau_self_loop": {
+			input: []byte(`L(d) = d'<d>.L(d)
+R(d) = d(x).R(d)
+$d.(L(d) | R(d))`),
+			sizes:    []int{1},
+			pathLens: []int{0},
+		},
+		"tau_cycle_after_output": {
+			input: []byte(`A(d) = d'<d>.B(d)
+B(d) = d'<d>.[d=d]A(d)
+R(d) = d(x).R(d)
+c'<c>.$d.(A(d) | R(d))`),
+			sizes:    []int{2},
+			pathLens: []int{2},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parents := getShortestPaths(lts)
+			var sizes []int
+			var pathLens []int
+			for _, scc := range getDivergences(lts) {
+				sizes = append(sizes, len(scc))
+				pathLens = append(pathLens, len(getPath(parents, scc[0])))
+				for _, id := range scc {
+					if _, ok := getDivergentStates([][]int{scc})[id]; !ok {
+						t.Errorf("s%d is not divergent", id)
+					}
+				}
+			}
+			if !reflect.DeepEqual(sizes, test.sizes) {
+				t.Errorf("got components of sizes %v, want %v", sizes, test.sizes)
+			}
+			if !reflect.DeepEqual(pathLens, test.pathLens) {
+				t.Errorf("got path lengths %v, want %v", pathLens, test.pathLens)
+			}
+		})
+	}
+}
+
+func TestMinimiseDivergences(t *testing.T) {
+	input := []byte(`A(d) = d'<d>.B(d)
+B(d) = d'<d>.[d=d]A(d)
+R(d) = d(x).R(d)
+c'<c>.$d.(A(d) | R(d))`)
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for _, equiv := range []string{EquivStrong, EquivWeak, EquivBranching} {
+		t.Run(equiv, func(t *testing.T) {
+			lts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lts.Divergent = getDivergentStates(getDivergences(lts))
+			lts, err = minimiseLts(lts, equiv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var highlighted int
+			for _, trn := range lts.Transitions {
+				if isDivergentTransition(lts, trn) {
+					highlighted++
+				}
+			}
+			if len(lts.Divergent) == 0 || highlighted == 0 {
+				t.Errorf("got %d divergent states and %d divergent transitions, want some of each",
+					len(lts.Divergent), highlighted)
+			}
+		})
+	}
+}
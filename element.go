@@ -27,6 +27,13 @@ type Name struct {
 	Type NameType
 }
 
+// Position is a position in the source of a model, where the first line and
+// column are 1. The zero position is unknown.
+type Position struct {
+	Line   int
+	Column int
+}
+
 type Element interface {
 	Type() ElementType
 }
@@ -41,6 +48,11 @@ type ElemOutput struct {
 	Channel Name
 	Output  Name
 	Next    Element
+	// The position of the prefix in the source.
+	Position Position
+	// The process definition of the component the prefix was unfolded
+	// from, if any.
+	Component string
 }
 
 func (e *ElemOutput) Type() ElementType {
@@ -51,6 +63,11 @@ type ElemInput struct {
 	Channel Name
 	Input   Name
 	Next    Element
+	// The position of the prefix in the source.
+	Position Position
+	// The process definition of the component the prefix was unfolded
+	// from, if any.
+	Component string
 }
 
 func (e *ElemInput) Type() ElementType {
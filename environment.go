@@ -0,0 +1,115 @@
+package pifra
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Models of the knowledge of the environment, which restrict the known names
+// it may send to the process. It may always send a fresh name.
+const (
+	// EnvironmentOutputs sends only the names previously output by the
+	// process, and the fresh names it has sent.
+	EnvironmentOutputs = "outputs"
+	// EnvironmentPublic sends only the marked names, i.e., "_"-prefixed
+	// names. Its knowledge is fixed.
+	EnvironmentPublic = "public"
+	// EnvironmentDolevYao sends the free names of the model, the names output
+	// by the process, and the fresh names it has sent.
+	EnvironmentDolevYao = "dolev-yao"
+)
+
+// environment is the model of the knowledge of the environment. If unset,
+// the environment may send any name of the register.
+var environment string
+
+// initKnowledge sets the names of the root known to the environment.
+func initKnowledge(root Configuration) Configuration {
+	if environment == "" {
+		return root
+	}
+	root.Registers.Known = make(map[string]bool)
+	for _, name := range root.Registers.Registers {
+		if environment == EnvironmentDolevYao ||
+			(environment == EnvironmentPublic && strings.HasPrefix(name, "_")) {
+			root.Registers.Known[name] = true
+		}
+	}
+	return root
+}
+
+// restrictEnvironment removes the transitions of a state on which the
+// environment sends a known name it does not know.
+func restrictEnvironment(state Configuration, confs []Configuration) []Configuration {
+	if environment == "" {
+		return confs
+	}
+	var restricted []Configuration
+	for _, conf := range confs {
+		label := conf.Label
+		if label.Symbol.Type == SymbolTypInput && label.Symbol2.Type == SymbolTypKnown &&
+			!state.Registers.Known[state.Registers.GetName(label.Symbol2.Value)] {
+			continue
+		}
+		restricted = append(restricted, conf)
+	}
+	return restricted
+}
+
+// updateKnowledge sets the names known to the environment after a
+// transition from a state to a normalised configuration. The names no longer
+// in the register are forgotten, and the fresh name of the transition is new
+// even if normalisation gives it the name of a forgotten one.
+func updateKnowledge(state Configuration, conf *Configuration) {
+	if environment == "" {
+		return
+	}
+	label := conf.Label
+	var fresh string
+	if label.Symbol2.Type == SymbolTypFreshInput || label.Symbol2.Type == SymbolTypFreshOutput {
+		fresh = conf.Registers.Registers[label.Symbol2.Value]
+	}
+
+	known := make(map[string]bool)
+	for _, name := range conf.Registers.Registers {
+		if name != fresh && state.Registers.Known[name] {
+			known[name] = true
+		}
+	}
+	switch {
+	case environment == EnvironmentPublic:
+	case label.Symbol.Type == SymbolTypOutput && label.Symbol2.Type == SymbolTypKnown:
+		// The name output is in the register unless it is forgotten.
+		name := state.Registers.GetName(label.Symbol2.Value)
+		if conf.Registers.GetLabel(name) != -1 {
+			known[name] = true
+		}
+	case fresh == "":
+		// The fresh name is forgotten, or there is none.
+	default:
+		// The fresh name is output, or sent by the environment itself.
+		known[fresh] = true
+	}
+	conf.Registers.Known = known
+}
+
+// prettyPrintLearnt returns the label of the name which the environment
+// learns by a transition of an LTS in square brackets, as the names known in
+// a register, or "" if it learns none.
+func prettyPrintLearnt(lts Lts, trn Transition) string {
+	src := lts.States[trn.Source].Registers
+	dst := lts.States[trn.Destination].Registers
+	label := trn.Label
+	var learnt bool
+	switch label.Symbol2.Type {
+	case SymbolTypKnown:
+		name := src.GetName(label.Symbol2.Value)
+		learnt = label.Symbol.Type == SymbolTypOutput && !src.Known[name] && dst.Known[name]
+	case SymbolTypFreshInput, SymbolTypFreshOutput:
+		learnt = dst.Known[dst.GetName(label.Symbol2.Value)]
+	}
+	if !learnt {
+		return ""
+	}
+	return "[" + strconv.Itoa(label.Symbol2.Value) + "]"
+}
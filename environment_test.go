@@ -0,0 +1,134 @@
+package pifra
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	tests := map[string]struct {
+		input     []byte
+		reachable map[string]bool
+	}{
+		"output_name_sent_back": {
+			input: []byte(`$s.a'<s>.a(x).[x=s]_BAD'<_BAD>.0`),
+			reachable: map[string]bool{
+				"":                  true,
+				EnvironmentOutputs:  true,
+				EnvironmentPublic:   false,
+				EnvironmentDolevYao: true,
+			},
+		},
+		"free_name_sent": {
+			input: []byte(`a(x).[x=b]_BAD'<_BAD>.0`),
+			reachable: map[string]bool{
+				"":                  true,
+				EnvironmentOutputs:  false,
+				EnvironmentPublic:   false,
+				EnvironmentDolevYao: true,
+			},
+		},
+		"public_name_sent": {
+			input: []byte(`a(x).[x=_PUB]_BAD'<_BAD>.0`),
+			reachable: map[string]bool{
+				"":                  true,
+				EnvironmentOutputs:  false,
+				EnvironmentPublic:   true,
+				EnvironmentDolevYao: true,
+			},
+		},
+		"fresh_name_sent_back": {
+			input: []byte(`a(x).b(y).[x=y]_BAD'<_BAD>.0`),
+			reachable: map[string]bool{
+				"":                  true,
+				EnvironmentOutputs:  true,
+				EnvironmentPublic:   true,
+				EnvironmentDolevYao: true,
+			},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	findName = "_BAD"
+	defer func() {
+		findName = ""
+		environment = ""
+	}()
+
+	for name, test := range tests {
+		for env, reachable := range test.reachable {
+			t.Run(name+"/"+env, func(t *testing.T) {
+				environment = env
+				lts, err := generateLts(test.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if (lts.Found != nil) != reachable {
+					t.Errorf("got _BAD reachable %t, want %t", lts.Found != nil, reachable)
+				}
+			})
+		}
+	}
+}
+
+func TestPrettyPrintLearnt(t *testing.T) {
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	environment = EnvironmentOutputs
+	defer func() {
+		environment = ""
+	}()
+
+	lts, err := generateLts([]byte(`$s.a'<s>.a(x).[x=s]b'<x>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(generatePrettyLts(lts))
+	for _, want := range []string{
+		"s0  1'3^ [3]  s1",
+		"s1  1 3   s2",
+		"s1  1 1* [1]  s3",
+		"s2  2'3   s4",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("got output\n%s\nwant %q", output, want)
+		}
+	}
+}
+
+func TestUpdateKnowledgeForgottenName(t *testing.T) {
+	// The known name #1 is forgotten, and the fresh name received is given
+	// the same name by normalisation.
+	state := Configuration{
+		Registers: Registers{
+			Size:      1,
+			Registers: map[int]string{1: "#1"},
+			Known:     map[string]bool{"#1": true},
+		},
+	}
+	conf := Configuration{
+		Registers: Registers{
+			Size:      1,
+			Registers: map[int]string{1: "#1"},
+		},
+		Label: Label{
+			Symbol:  Symbol{Type: SymbolTypInput, Value: 1},
+			Symbol2: Symbol{Type: SymbolTypFreshInput, Value: 1},
+		},
+	}
+	defer func() {
+		environment = ""
+	}()
+
+	for env, known := range map[string]bool{
+		EnvironmentOutputs:  true,
+		EnvironmentPublic:   false,
+		EnvironmentDolevYao: true,
+	} {
+		environment = env
+		updateKnowledge(state, &conf)
+		if conf.Registers.Known["#1"] != known {
+			t.Errorf("%s: got #1 known %t, want %t", env, !known, known)
+		}
+	}
+}
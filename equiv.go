@@ -0,0 +1,411 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bisimPair is a pair of states of two LTSs with a partial bijection from the
+// register labels of the left state to the register labels of the right state
+// holding the same names. Unrelated labels hold names known to one side only.
+type bisimPair struct {
+	Left      int
+	Right     int
+	Bijection map[int]int
+	// The identities of the names at the labels of each state, equal for
+	// equal names, when deciding symbolic bisimilarity.
+	Ids [2]map[int]int
+}
+
+// bisimMove is a challenge by one side of a pair: a transition of the mover,
+// and for a fresh input, the label of the name received if it is a name known
+// only to the other side (0 if it is fresh to both).
+type bisimMove struct {
+	Side       int
+	Transition Transition
+	OtherKnown int
+	Matches    []bisimMatch
+}
+
+// bisimMatch is a transition of the other side matching a challenge, and the
+// resulting pair.
+type bisimMatch struct {
+	Transition Transition
+	Pair       int
+}
+
+// bisimStep is a step of a distinguishing trace. The last step has no match.
+type bisimStep struct {
+	Side       int
+	Transition Transition
+	OtherKnown int
+	Other      int
+	Match      *Transition
+}
+
+type bisimResult struct {
+	Bisimilar bool
+	// Whether a pair with an unexplored state was reached, in which case the
+	// pair is assumed to be bisimilar.
+	Bounded bool
+	Pairs   int
+	Trace   []bisimStep
+	// A formula which the left root satisfies and the right root does not.
+	Formula hmlFormula
+}
+
+// bisimChecker decides bisimilarity of two LTSs on the pairs reachable from
+// their roots. The greatest bisimulation is computed by removing pairs with a
+// challenge which cannot be matched by a pair not yet removed.
+type bisimChecker struct {
+	lts  [2]Lts
+	weak bool
+	// Transitions of each side by source, which challenge and which match.
+	moveTrns  [2]map[int][]Transition
+	matchTrns [2]map[int][]Transition
+
+	pairs   []bisimPair
+	pairIds map[string]int
+	moves   [][]bisimMove
+	bounded bool
+}
+
+// checkBisimilarity decides whether the roots of two LTSs are strongly or
+// weakly bisimilar. Labels are compared as FRA labels: known labels must be
+// related by the bijection, a name known to one side only is fresh to the
+// other, and fresh names extend the bijection.
+func checkBisimilarity(left Lts, right Lts, weak bool) bisimResult {
+	c := &bisimChecker{
+		lts:     [2]Lts{left, right},
+		weak:    weak,
+		pairIds: make(map[string]int),
+	}
+	for side, lts := range c.lts {
+		c.moveTrns[side] = getTransitionsBySource(lts.Transitions)
+		if weak {
+			c.matchTrns[side] = getTransitionsBySource(saturateTau(len(lts.States), lts.Transitions))
+		} else {
+			c.matchTrns[side] = c.moveTrns[side]
+		}
+	}
+
+	c.addPair(0, 0, getRootBijection(left, right))
+	for id := 0; id < len(c.pairs); id++ {
+		c.moves = append(c.moves, c.getMoves(id))
+	}
+
+	rank, witness := c.getRanks()
+	result := bisimResult{
+		Bisimilar: rank[0] == 0,
+		Bounded:   c.bounded,
+		Pairs:     len(c.pairs),
+	}
+	if !result.Bisimilar {
+		result.Trace = c.getTrace(rank, witness)
+		result.Formula = c.getFormula(0, rank, witness, make(map[int]hmlFormula))
+	}
+	return result
+}
+
+func getTransitionsBySource(trns []Transition) map[int][]Transition {
+	bySource := make(map[int][]Transition)
+	for _, trn := range trns {
+		bySource[trn.Source] = append(bySource[trn.Source], trn)
+	}
+	return bySource
+}
+
+// getRootNames returns the original names in the register of the root.
+func getRootNames(lts Lts) map[int]string {
+	names := make(map[int]string)
+	root := lts.States[0]
+	for label, name := range root.Registers.Registers {
+		if original, ok := lts.FreeNamesMap[name]; ok {
+			name = original
+		}
+		names[label] = name
+	}
+	return names
+}
+
+// getRootBijection relates the register labels of the roots holding the same
+// original names.
+func getRootBijection(left Lts, right Lts) map[int]int {
+	rightLabels := make(map[string]int)
+	for label, name := range getRootNames(right) {
+		rightLabels[name] = label
+	}
+	bijection := make(map[int]int)
+	for label, name := range getRootNames(left) {
+		if rightLabel, ok := rightLabels[name]; ok {
+			bijection[label] = rightLabel
+		}
+	}
+	return bijection
+}
+
+func getBijectionKey(bijection map[int]int) string {
+	var pairs []string
+	for i, j := range bijection {
+		pairs = append(pairs, strconv.Itoa(i)+":"+strconv.Itoa(j))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func invertBijection(bijection map[int]int) map[int]int {
+	inv := make(map[int]int)
+	for i, j := range bijection {
+		inv[j] = i
+	}
+	return inv
+}
+
+// extendBijection relates i to j, removing any other pair with i or j.
+func extendBijection(bijection map[int]int, i int, j int) map[int]int {
+	ext := map[int]int{i: j}
+	for k, v := range bijection {
+		if k != i && v != j {
+			ext[k] = v
+		}
+	}
+	return ext
+}
+
+// addPair adds a pair, restricting the bijection to the labels in the
+// registers of both states, and returns its ID.
+func (c *bisimChecker) addPair(left int, right int, bijection map[int]int) int {
+	leftReg := c.lts[0].States[left].Registers.Registers
+	rightReg := c.lts[1].States[right].Registers.Registers
+	restricted := make(map[int]int)
+	for i, j := range bijection {
+		_, okL := leftReg[i]
+		_, okR := rightReg[j]
+		if okL && okR {
+			restricted[i] = j
+		}
+	}
+
+	key := strconv.Itoa(left) + ";" + strconv.Itoa(right) + ";" + getBijectionKey(restricted)
+	if id, ok := c.pairIds[key]; ok {
+		return id
+	}
+	id := len(c.pairs)
+	c.pairIds[key] = id
+	c.pairs = append(c.pairs, bisimPair{
+		Left:      left,
+		Right:     right,
+		Bijection: restricted,
+	})
+	return id
+}
+
+// getMoves returns the challenges of both sides of a pair with their matches,
+// adding the resulting pairs. A pair with an unexplored state has none.
+func (c *bisimChecker) getMoves(id int) []bisimMove {
+	pair := c.pairs[id]
+	if !c.lts[0].isExplored(pair.Left) || !c.lts[1].isExplored(pair.Right) {
+		c.bounded = true
+		return nil
+	}
+
+	var moves []bisimMove
+	for side := 0; side < 2; side++ {
+		mover, other := pair.Left, pair.Right
+		bijection := pair.Bijection
+		if side == 1 {
+			mover, other = other, mover
+			bijection = invertBijection(bijection)
+		}
+		related := make(map[int]bool)
+		for _, j := range bijection {
+			related[j] = true
+		}
+
+		for _, trn := range c.moveTrns[side][mover] {
+			// A fresh input may receive a name fresh to both sides, or a name
+			// known only to the other side.
+			otherKnowns := []int{0}
+			if trn.Label.Symbol2.Type == SymbolTypFreshInput {
+				otherReg := c.lts[1-side].States[other].Registers
+				for _, j := range otherReg.Labels() {
+					if !related[j] {
+						otherKnowns = append(otherKnowns, j)
+					}
+				}
+			}
+			for _, otherKnown := range otherKnowns {
+				move := bisimMove{
+					Side:       side,
+					Transition: trn,
+					OtherKnown: otherKnown,
+				}
+				for _, otrn := range c.matchTrns[1-side][other] {
+					ext, ok := matchLabel(trn.Label, otrn.Label, bijection, otherKnown)
+					if !ok {
+						continue
+					}
+					var pairId int
+					if side == 0 {
+						pairId = c.addPair(trn.Destination, otrn.Destination, ext)
+					} else {
+						pairId = c.addPair(otrn.Destination, trn.Destination, invertBijection(ext))
+					}
+					move.Matches = append(move.Matches, bisimMatch{
+						Transition: otrn,
+						Pair:       pairId,
+					})
+				}
+				moves = append(moves, move)
+			}
+		}
+	}
+	return moves
+}
+
+// matchLabel returns whether the label of the other side matches the label of
+// the mover, and the bijection extended with the names received or created.
+func matchLabel(label Label, otherLabel Label, bijection map[int]int, otherKnown int) (map[int]int, bool) {
+	if label.Symbol.Type != otherLabel.Symbol.Type {
+		return nil, false
+	}
+	if label.Symbol.Type == SymbolTypTau {
+		return bijection, true
+	}
+	if j, ok := bijection[label.Symbol.Value]; !ok || j != otherLabel.Symbol.Value {
+		return nil, false
+	}
+
+	i := label.Symbol2.Value
+	j := otherLabel.Symbol2.Value
+	switch label.Symbol2.Type {
+	case SymbolTypKnown:
+		if rj, ok := bijection[i]; ok {
+			return bijection, otherLabel.Symbol2.Type == SymbolTypKnown && j == rj
+		}
+		// A name known only to the mover is fresh to the other side.
+		if label.Symbol.Type == SymbolTypInput && otherLabel.Symbol2.Type == SymbolTypFreshInput {
+			return extendBijection(bijection, i, j), true
+		}
+	case SymbolTypFreshInput:
+		if otherKnown != 0 {
+			if otherLabel.Symbol2.Type == SymbolTypKnown && j == otherKnown {
+				return extendBijection(bijection, i, j), true
+			}
+		} else if otherLabel.Symbol2.Type == SymbolTypFreshInput {
+			return extendBijection(bijection, i, j), true
+		}
+	case SymbolTypFreshOutput:
+		if otherLabel.Symbol2.Type == SymbolTypFreshOutput {
+			return extendBijection(bijection, i, j), true
+		}
+	}
+	return nil, false
+}
+
+// getRanks returns the round in which each pair was removed (0 if it is in
+// the greatest bisimulation), and the challenge which removed it. A pair is
+// removed in a round if it has a challenge whose matches were all removed in
+// earlier rounds.
+func (c *bisimChecker) getRanks() ([]int, []int) {
+	rank := make([]int, len(c.pairs))
+	witness := make([]int, len(c.pairs))
+	for round := 1; ; round++ {
+		var removed []int
+		for id := range c.pairs {
+			if rank[id] != 0 {
+				continue
+			}
+			for i, move := range c.moves[id] {
+				matched := false
+				for _, match := range move.Matches {
+					if rank[match.Pair] == 0 {
+						matched = true
+						break
+					}
+				}
+				if !matched {
+					witness[id] = i
+					removed = append(removed, id)
+					break
+				}
+			}
+		}
+		if len(removed) == 0 {
+			return rank, witness
+		}
+		for _, id := range removed {
+			rank[id] = round
+		}
+	}
+}
+
+// getTrace returns a distinguishing trace from the roots, following the
+// challenges which removed each pair to a challenge which cannot be matched.
+func (c *bisimChecker) getTrace(rank []int, witness []int) []bisimStep {
+	var trace []bisimStep
+	id := 0
+	for {
+		pair := c.pairs[id]
+		move := c.moves[id][witness[id]]
+		other := pair.Right
+		if move.Side == 1 {
+			other = pair.Left
+		}
+		step := bisimStep{
+			Side:       move.Side,
+			Transition: move.Transition,
+			OtherKnown: move.OtherKnown,
+			Other:      other,
+		}
+		if len(move.Matches) == 0 {
+			return append(trace, step)
+		}
+
+		// Follow the match removed earliest.
+		next := move.Matches[0]
+		for _, match := range move.Matches {
+			if rank[match.Pair] < rank[next.Pair] {
+				next = match
+			}
+		}
+		trn := next.Transition
+		step.Match = &trn
+		trace = append(trace, step)
+		id = next.Pair
+	}
+}
+
+// prettyPrintBisimTrace returns a distinguishing trace with a line per step,
+// giving the transitions of both sides.
+func prettyPrintBisimTrace(trace []bisimStep, names [2]string) string {
+	width := len(names[0])
+	if len(names[1]) > width {
+		width = len(names[1])
+	}
+	pad := func(str string) string {
+		return str + strings.Repeat(" ", width-len(str))
+	}
+	prettyPrintTrn := func(trn Transition) string {
+		return "s" + strconv.Itoa(trn.Source) + "  " + PrettyPrintLabel(trn.Label) +
+			"  s" + strconv.Itoa(trn.Destination)
+	}
+
+	var lines []string
+	for _, step := range trace {
+		strs := [2]string{}
+		strs[step.Side] = prettyPrintTrn(step.Transition)
+		if step.Match != nil {
+			strs[1-step.Side] = prettyPrintTrn(*step.Match)
+		} else {
+			strs[1-step.Side] = "s" + strconv.Itoa(step.Other) + "  cannot match"
+			if step.OtherKnown != 0 {
+				strs[1-step.Side] = strs[1-step.Side] + " receiving the name at " +
+					strconv.Itoa(step.OtherKnown)
+			}
+		}
+		lines = append(lines, pad(names[0])+"  "+strs[0], pad(names[1])+"  "+strs[1])
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,144 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestCheckBisimilarity(t *testing.T) {
+	tests := map[string]struct {
+		spec      []byte
+		impl      []byte
+		strong    bool
+		weak      bool
+		traceLens [2]int
+		// Whether the registers of the models coincide, so that the formula
+		// can be checked on the implementation directly.
+		sameRegisters bool
+	}{
+		"identical": {
+			spec:   []byte(`a(x).x'<x>.0`),
+			impl:   []byte(`a(y).y'<y>.0`),
+			strong: true,
+			weak:   true,
+		},
+		"different_registers": {
+			spec:   []byte(`b'<a>.0`),
+			impl:   []byte(`[a0=a0]b'<a>.0`),
+			strong: true,
+			weak:   true,
+		},
+		"fresh_output_channel": {
+			spec:      []byte(`a(x).x'<x>.0`),
+			impl:      []byte(`a(x).a'<x>.0`),
+			traceLens: [2]int{2, 2},
+		},
+		"internal_step": {
+			spec:          []byte(`a(x).x'<x>.0`),
+			impl:          []byte(`a(y).$c.(c'<c>.0 | c(z).y'<y>.0)`),
+			strong:        false,
+			weak:          true,
+			traceLens:     [2]int{2, 0},
+			sameRegisters: true,
+		},
+		"name_known_to_impl": {
+			spec:      []byte(`a(x).x'<x>.0`),
+			impl:      []byte(`a(x).[x!=b]x'<x>.0`),
+			traceLens: [2]int{2, 2},
+		},
+		"fresh_output": {
+			spec:          []byte(`$x.a'<x>.x(y).0`),
+			impl:          []byte(`$x.a'<x>.a(y).0`),
+			traceLens:     [2]int{2, 2},
+			sameRegisters: true,
+		},
+		"branching": {
+			spec:          []byte(`a'<a>.(b'<b>.0 + c'<c>.0)`),
+			impl:          []byte(`a'<a>.b'<b>.0 + a'<a>.c'<c>.0`),
+			traceLens:     [2]int{2, 2},
+			sameRegisters: true,
+		},
+		"matches_with_different_labels": {
+			spec:          []byte(`a(x).a'<x>.0 + a(y).b'<y>.0`),
+			impl:          []byte(`a(x).a'<x>.0 + a(y).b'<y>.0 + a(z).z'<z>.0`),
+			traceLens:     [2]int{2, 2},
+			sameRegisters: true,
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec, err := generateLts(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			impl, err := generateLts(test.impl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, weak := range []bool{false, true} {
+				want := test.strong
+				if weak {
+					want = test.weak
+				}
+				result := checkBisimilarity(spec, impl, weak)
+				if result.Bisimilar != want {
+					t.Errorf("weak %t: got bisimilar %t, want %t", weak, result.Bisimilar, want)
+				}
+				if result.Bounded {
+					t.Errorf("weak %t: unexpected unexplored pair", weak)
+				}
+				if len(result.Trace) != test.traceLens[i] {
+					t.Errorf("weak %t: got trace of length %d, want %d\n%s", weak, len(result.Trace),
+						test.traceLens[i], prettyPrintBisimTrace(result.Trace, [2]string{"spec", "impl"}))
+				}
+				if !result.Bisimilar {
+					trns := [2]map[int][]Transition{
+						getTransitionsBySource(spec.Transitions),
+						getTransitionsBySource(impl.Transitions),
+					}
+					if weak {
+						trns[0] = getTransitionsBySource(saturateTau(len(spec.States), spec.Transitions))
+						trns[1] = getTransitionsBySource(saturateTau(len(impl.States), impl.Transitions))
+					}
+					formula := prettyPrintHml(result.Formula)
+					if !satisfiesHml(trns[0], 0, result.Formula) {
+						t.Errorf("weak %t: spec does not satisfy %s", weak, formula)
+					}
+					if test.sameRegisters && satisfiesHml(trns[1], 0, result.Formula) {
+						t.Errorf("weak %t: impl satisfies %s", weak, formula)
+					}
+				}
+				// The relation is symmetric.
+				if checkBisimilarity(impl, spec, weak).Bisimilar != want {
+					t.Errorf("weak %t: not symmetric", weak)
+				}
+			}
+		})
+	}
+}
+
+// satisfiesHml returns whether a state satisfies a formula, comparing labels
+// as they are.
+func satisfiesHml(trns map[int][]Transition, state int, formula hmlFormula) bool {
+	switch formula.Type {
+	case hmlNot:
+		return !satisfiesHml(trns, state, formula.Subs[0])
+	case hmlAnd:
+		for _, sub := range formula.Subs {
+			if !satisfiesHml(trns, state, sub) {
+				return false
+			}
+		}
+		return true
+	case hmlDiamond:
+		for _, trn := range trns[state] {
+			if trn.Label == formula.Label && satisfiesHml(trns, trn.Destination, formula.Subs[0]) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package pifra
+
+// evictRegisters bounds the registers to the register size by evicting the
+// names not free in the process, before leaving unexplored the states whose
+// register still exceeds it.
+var evictRegisters bool
+
+// fitRegister evicts names not free in the process from the register of a
+// normalised configuration, from the greatest label, until it fits the
+// register size, and returns the number of names evicted. Free names are never
+// evicted, so the register may still exceed the register size. The labels of
+// the other names are kept, and fresh names take the least free label.
+func fitRegister(conf *Configuration) int {
+	reg := conf.Registers.Registers
+	if !evictRegisters || len(reg) <= registerSize {
+		return 0
+	}
+
+	freeNames := make(map[string]bool)
+	for _, name := range GetAllFreeNames(conf.Process) {
+		freeNames[name] = true
+	}
+	labels := conf.Registers.Labels()
+	var evicted int
+	for i := len(labels) - 1; i >= 0 && len(reg) > registerSize; i-- {
+		if !freeNames[reg[labels[i]]] {
+			delete(reg, labels[i])
+			evicted++
+		}
+	}
+	return evicted
+}
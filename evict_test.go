@@ -0,0 +1,136 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestEvictRegisters(t *testing.T) {
+	tests := map[string]struct {
+		input       []byte
+		registers   int
+		disableGC   bool
+		states      int
+		transitions int
+		evicted     bool
+		reached     int
+	}{
+		"fits": {
+			input:       []byte(`a(x).a(y).x'<y>.0`),
+			registers:   2,
+			states:      8,
+			transitions: 11,
+		},
+		"free_names_kept": {
+			input:       []byte(`a(x).a(y).x'<y>.0`),
+			registers:   1,
+			states:      6,
+			transitions: 5,
+			reached:     2,
+		},
+		"dead_names_evicted": {
+			input:       []byte(`a'<a>.0 | b'<b>.0 | $n.b'<n>.0`),
+			registers:   2,
+			disableGC:   true,
+			states:      8,
+			transitions: 12,
+			evicted:     true,
+		},
+	}
+	maxStatesExplored = 100
+	defer func() {
+		registerSize = 1073741824
+		disableGarbageCollection = false
+		evictRegisters = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			registerSize = test.registers
+			disableGarbageCollection = test.disableGC
+			evictRegisters = true
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lts.States) != test.states {
+				t.Errorf("got %d states, want %d", len(lts.States), test.states)
+			}
+			if len(lts.Transitions) != test.transitions {
+				t.Errorf("got %d transitions, want %d", len(lts.Transitions), test.transitions)
+			}
+			if (lts.NamesEvicted > 0) != test.evicted {
+				t.Errorf("got %d names evicted, want evicted %t", lts.NamesEvicted, test.evicted)
+			}
+			if len(lts.RegSizeReached) != test.reached {
+				t.Errorf("got %d states over the registers, want %d", len(lts.RegSizeReached), test.reached)
+			}
+			for id, state := range lts.States {
+				if !lts.RegSizeReached[id] && len(state.Registers.Registers) > test.registers {
+					t.Errorf("state %d has %d registers, want at most %d",
+						id, len(state.Registers.Registers), test.registers)
+				}
+			}
+		})
+	}
+}
+
+func TestEvictRegistersFreeNames(t *testing.T) {
+	registerSize = 1
+	evictRegisters = true
+	defer func() {
+		registerSize = 1073741824
+		evictRegisters = false
+	}()
+	if _, err := generateLts([]byte(`a'<b>.0`)); err == nil {
+		t.Error("got no error for free names exceeding the registers")
+	}
+}
+
+func TestFitRegister(t *testing.T) {
+	tests := map[string]struct {
+		input     []byte
+		disableGC bool
+		registers map[int]string
+		evicted   int
+		key       string
+	}{
+		"dead_name_evicted": {
+			input:     []byte(`a'<c>.0`),
+			disableGC: true,
+			registers: map[int]string{1: "#1", 2: "#3", 4: "#2"},
+			evicted:   1,
+			key:       "{(1,#1),(4,#2)}#1'<#2>.0",
+		},
+		"free_names_kept": {
+			input:     []byte(`a'<b>.c'<c>.0`),
+			registers: map[int]string{1: "#1", 2: "#2", 3: "#3"},
+			key:       "{(1,#1),(2,#2),(3,#3)}#1'<#2>.#3'<#3>.0",
+		},
+	}
+	registerSize = 2
+	evictRegisters = true
+	defer func() {
+		registerSize = 1073741824
+		disableGarbageCollection = false
+		evictRegisters = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			disableGarbageCollection = test.disableGC
+			proc, err := InitProgram(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf, _ := newRootConf(proc)
+			conf.Registers.Registers = test.registers
+			applyStructrualCongruence(conf)
+			if evicted := fitRegister(&conf); evicted != test.evicted {
+				t.Errorf("got %d names evicted, want %d", evicted, test.evicted)
+			}
+			if key := getConfigurationKey(conf); key != test.key {
+				t.Errorf("got key %s, want %s", key, test.key)
+			}
+		})
+	}
+}
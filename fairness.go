@@ -0,0 +1,149 @@
+package pifra
+
+import (
+	"strconv"
+
+	"github.com/mohae/deepcopy"
+)
+
+// Fairness assumptions on the parallel components of a state.
+const (
+	// FairnessWeak excludes the paths on which a component is enabled at
+	// every state from some point on, but never moves.
+	FairnessWeak = "weak"
+	// FairnessStrong excludes the paths on which a component is enabled
+	// infinitely often, but moves only finitely often.
+	FairnessStrong = "strong"
+)
+
+// componentInfo is the parallel components which move in each transition of
+// an LTS, and the components enabled at each state, i.e., which move in some
+// transition of the state.
+type componentInfo struct {
+	Moves   map[Transition]map[string]bool
+	Enabled map[int]map[string]bool
+}
+
+// frozenName marks the component whose transitions are excluded by
+// getComponentInfo.
+const frozenName = "@frozen"
+
+// getComponentKeys returns keys identifying the top-level parallel components
+// of a state, with free names written as their register labels.
+func getComponentKeys(conf Configuration) []string {
+	_, comps := getComponents(conf.Process)
+	var keys []string
+	for _, comp := range comps {
+		compConf := Configuration{
+			Process: &ElemRoot{
+				Next: deepcopy.Copy(comp).(Element),
+			},
+			Registers: deepcopy.Copy(conf.Registers).(Registers),
+		}
+		for label, name := range conf.Registers.Registers {
+			subName(compConf.Process, Name{
+				Name: name,
+			}, Name{
+				Name: "@" + strconv.Itoa(label),
+			})
+		}
+		normaliseBoundNames(compConf)
+		keys = append(keys, PrettyPrintAst(compConf.Process))
+	}
+	return keys
+}
+
+// getComponentInfo returns the components which move in each transition, and
+// the components enabled at each state.
+func getComponentInfo(lts Lts) componentInfo {
+	info := componentInfo{
+		Moves:   make(map[Transition]map[string]bool),
+		Enabled: make(map[int]map[string]bool),
+	}
+	stateIds := make(map[string]int)
+	for id, conf := range lts.States {
+		stateIds[getConfigurationKey(conf)] = id
+		info.Enabled[id] = make(map[string]bool)
+	}
+	trns := getTransitionsBySource(lts.Transitions)
+	for _, trn := range lts.Transitions {
+		info.Moves[trn] = make(map[string]bool)
+	}
+
+	for id, conf := range lts.States {
+		if len(trns[id]) == 0 {
+			continue
+		}
+		conf = deepcopy.Copy(conf).(Configuration)
+		resNames, comps := getComponents(conf.Process)
+		keys := getComponentKeys(conf)
+		frozenKeys := make(map[string]bool)
+		for i := range comps {
+			key := keys[i]
+			if comps[i].Type() == ElemTypNil || frozenKeys[key] {
+				continue
+			}
+			frozenKeys[key] = true
+			// Freeze the identical components together, as they have the
+			// same key.
+			procs := deepcopy.Copy(comps).([]Element)
+			for j := range procs {
+				if keys[j] == key {
+					procs[j] = &ElemEquality{
+						Inequality: true,
+						NameL:      Name{Name: frozenName},
+						NameR:      Name{Name: frozenName},
+						Next:       procs[j],
+					}
+				}
+			}
+			frozenConf := Configuration{
+				Process:   buildComponents(resNames, procs),
+				Registers: deepcopy.Copy(conf.Registers).(Registers),
+			}
+			idle := make(map[Transition]bool)
+			for _, tconf := range trans(frozenConf) {
+				tconf.Process = thawComponent(tconf.Process)
+				applyStructrualCongruence(tconf)
+				fitRegister(&tconf)
+				updateKnowledge(conf, &tconf)
+				if dst, ok := stateIds[getConfigurationKey(tconf)]; ok {
+					idle[Transition{
+						Source:      id,
+						Destination: dst,
+						Label:       tconf.Label,
+					}] = true
+				}
+			}
+			for _, trn := range trns[id] {
+				if !idle[trn] {
+					info.Moves[trn][key] = true
+					info.Enabled[id][key] = true
+				}
+			}
+		}
+	}
+	return info
+}
+
+// thawComponent removes the matches freezing components.
+func thawComponent(elem Element) Element {
+	switch elem.Type() {
+	case ElemTypMatch:
+		matchElem := elem.(*ElemEquality)
+		if matchElem.NameL.Name == frozenName {
+			return matchElem.Next
+		}
+	case ElemTypRestriction:
+		resElem := elem.(*ElemRestriction)
+		resElem.Next = thawComponent(resElem.Next)
+	case ElemTypParallel:
+		parElem := elem.(*ElemParallel)
+		parElem.ProcessL = thawComponent(parElem.ProcessL)
+		parElem.ProcessR = thawComponent(parElem.ProcessR)
+	case ElemTypRoot:
+		rootElem := elem.(*ElemRoot)
+		rootElem.Next = thawComponent(rootElem.Next)
+	}
+	return elem
+}
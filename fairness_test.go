@@ -0,0 +1,59 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetComponentInfo(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		// The number of components moving in each transition from s0.
+		moves []int
+	}{
+		"interleaving": {
+			input: []byte(`
+P(a) = a'<a>.P(a)
+Q(b) = b'<b>.Q(b)
+P(a) | Q(b)
+`),
+			moves: []int{1, 1},
+		},
+		"identical_components": {
+			input: []byte(`
+P(a) = a'<a>.P(a)
+P(a) | P(a)
+`),
+			moves: []int{1},
+		},
+		"nested_restriction": {
+			input: []byte(`$c.(c'<c>.0 | c(x).a'<x>.0) | b'<b>.0`),
+			moves: []int{1, 1},
+		},
+		"communication": {
+			input: []byte(`$c.(c'<c>.a'<a>.0 | c(x).b'<b>.0)`),
+			moves: []int{2},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := getComponentInfo(lts)
+			var moves []int
+			for _, trn := range lts.Transitions {
+				if trn.Source == 0 {
+					moves = append(moves, len(info.Moves[trn]))
+				}
+			}
+			if !reflect.DeepEqual(moves, test.moves) {
+				t.Errorf("got moves %v, want %v", moves, test.moves)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package pifra
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// findName is the marked name searched for. If set, exploration stops at the
+// first transition whose channel is the name.
+var findName string
+
+// isFindLabel returns whether the channel of a label of a transition from a
+// state is the name searched for.
+func isFindLabel(state Configuration, label Label) bool {
+	if findName == "" || label.Symbol.Type == SymbolTypTau {
+		return false
+	}
+	return state.Registers.GetName(label.Symbol.Value) == findName
+}
+
+// getNamedTrace returns the labels of a path from the root with the names of
+// the registers instead of their labels. Names of the root are written with
+// their original names, and names created or received along the path are
+// written #1, #2, etc.
+func getNamedTrace(lts Lts, path []Transition) []string {
+	names := getRootNames(lts)
+	var fresh int
+	var labels []string
+	for _, trn := range path {
+		label := trn.Label
+		if label.Symbol.Type == SymbolTypTau {
+			labels = append(labels, "t")
+			continue
+		}
+		// The channel is in the register of the source, and a fresh name in
+		// the register of the destination, possibly at the same label.
+		str := names[label.Symbol.Value]
+		if label.Symbol2.Type == SymbolTypFreshInput || label.Symbol2.Type == SymbolTypFreshOutput {
+			fresh++
+			names[label.Symbol2.Value] = fnPrefix + strconv.Itoa(fresh)
+		}
+		if label.Symbol.Type == SymbolTypOutput {
+			str = str + "'"
+		} else {
+			str = str + " "
+		}
+		str = str + names[label.Symbol2.Value]
+		switch label.Symbol2.Type {
+		case SymbolTypFreshInput:
+			str = str + "*"
+		case SymbolTypFreshOutput:
+			str = str + "^"
+		}
+		labels = append(labels, str)
+	}
+	return labels
+}
+
+// generateFindReport returns a shortest trace from the root ending with a
+// transition on the name searched for, or whether the name is unreachable
+// within the bounds of exploration.
+func generateFindReport(lts Lts, name string) []byte {
+	var buffer bytes.Buffer
+	if lts.Found == nil {
+		var unexplored int
+		for id := range lts.States {
+			if !lts.isExplored(id) {
+				unexplored++
+			}
+		}
+		if unexplored == 0 {
+			buffer.WriteString(name + " is unreachable")
+		} else {
+			buffer.WriteString(name + " is unreachable within the bounds\n")
+			buffer.WriteString("states unexplored    " + strconv.Itoa(unexplored))
+		}
+		return buffer.Bytes()
+	}
+
+	path := append(getPath(getShortestPaths(lts), lts.Found.Source), *lts.Found)
+	steps := " steps"
+	if len(path) == 1 {
+		steps = " step"
+	}
+	buffer.WriteString(name + " is reachable in " + strconv.Itoa(len(path)) + steps)
+	for i, label := range getNamedTrace(lts, path) {
+		buffer.WriteString("\ns" + strconv.Itoa(path[i].Source) + "  " + label +
+			"  s" + strconv.Itoa(path[i].Destination))
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,82 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := map[string]struct {
+		input     []byte
+		maxStates int
+		trace     []string
+		explored  int
+		trns      int
+	}{
+		"received_marked_name": {
+			input:     []byte(`a(x).$y.x'<y>.(_BAD'<x>.0 | b'<b>.0)`),
+			maxStates: 100,
+			trace:     []string{"a _BAD", "_BAD'#1^"},
+			explored:  2,
+		},
+		"original_names": {
+			input:     []byte(`b'<a>.a(x).[x=b]_BAD'<x>.0`),
+			maxStates: 100,
+			trace:     []string{"b'a", "a b", "_BAD'b"},
+			explored:  5,
+		},
+		"fresh_names": {
+			input:     []byte(`$c.a'<c>.c(x).x'<c>._BAD'<x>.0`),
+			maxStates: 100,
+			trace:     []string{"a'#1^", "#1 _BAD", "_BAD'#1"},
+			explored:  3,
+		},
+		"source_state_explored": {
+			input:     []byte(`_BAD'<_BAD>.0 | _C(x).0`),
+			maxStates: 100,
+			trace:     []string{"_BAD'_BAD"},
+			explored:  1,
+			trns:      4,
+		},
+		"unreachable": {
+			input:     []byte(`a(x).[x=b]a'<a>.0`),
+			maxStates: 100,
+		},
+		"unreachable_within_bounds": {
+			input:     []byte(`P(a) = a(x).P(a)` + "\n" + `a'<a>.P(a) | a'<a>.a'<a>.a'<a>._BAD'<_BAD>.0`),
+			maxStates: 2,
+		},
+	}
+	registerSize = 1073741824
+	findName = "_BAD"
+	defer func() {
+		findName = ""
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			maxStatesExplored = test.maxStates
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lts.Found == nil {
+				if test.trace != nil {
+					t.Fatal("transition on _BAD not found")
+				}
+				return
+			}
+			path := append(getPath(getShortestPaths(lts), lts.Found.Source), *lts.Found)
+			trace := getNamedTrace(lts, path)
+			if !reflect.DeepEqual(trace, test.trace) {
+				t.Errorf("got trace %v, want %v", trace, test.trace)
+			}
+			if lts.StatesExplored != test.explored {
+				t.Errorf("got %d states explored, want %d", lts.StatesExplored, test.explored)
+			}
+			if test.trns != 0 && len(lts.Transitions) != test.trns {
+				t.Errorf("got %d transitions, want %d", len(lts.Transitions), test.trns)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package pifra
+
+import (
+	"fmt"
+)
+
+// Commands of which the flags are validated, as named on the command line.
+// CommandLts generates an LTS or runs the REPL.
+const (
+	CommandLts      = ""
+	CommandEquiv    = "equiv"
+	CommandTraces   = "traces-included"
+	CommandCheck    = "check"
+	CommandLtl      = "ltl"
+	CommandLate     = "late"
+	CommandSimulate = "simulate"
+	CommandStep     = "step"
+	CommandAccepts  = "accepts"
+	CommandPath     = "path"
+	CommandMsc      = "msc"
+)
+
+// ValidateFlags returns an error if a flag has an invalid value or is not
+// supported with the other flags by a command. A register size of 0 is
+// unlimited.
+func ValidateFlags(flags Flags, command string) error {
+	if command == CommandLate {
+		flags.Late = true
+	}
+	reduced := flags.PartialOrder || flags.Symmetry
+
+	if flags.Evict && flags.RegisterSize == 0 {
+		return fmt.Errorf("--evict requires --max-registers")
+	}
+	if flags.Find != "" && flags.Find[0] != '_' {
+		return fmt.Errorf("name searched for must be a marked name, e.g., _BAD")
+	}
+	switch flags.Minimise {
+	case "", EquivStrong, EquivWeak, EquivBranching:
+	default:
+		return fmt.Errorf("minimisation must be strong, weak or branching")
+	}
+	switch flags.Fairness {
+	case "", FairnessWeak, FairnessStrong:
+	default:
+		return fmt.Errorf("fairness must be weak or strong")
+	}
+	switch flags.Environment {
+	case "", EnvironmentOutputs, EnvironmentPublic, EnvironmentDolevYao:
+	default:
+		return fmt.Errorf("environment must be outputs, public or dolev-yao")
+	}
+	if flags.Environment != "" && reduced {
+		return fmt.Errorf("partial-order and symmetry reduction do not support a restricted environment")
+	}
+	if flags.Observe && !flags.Closed && !flags.PartialOrder {
+		return fmt.Errorf("--observe-marked requires --closed or --por")
+	}
+	if flags.Late && flags.Explain {
+		return fmt.Errorf("--explain does not support --late, whose instantiations are not derived by rules")
+	}
+	if flags.Late && flags.Symbolic {
+		return fmt.Errorf("late and symbolic semantics are exclusive")
+	}
+	if (flags.Late || flags.Symbolic) && (reduced || flags.Environment != "" || flags.Find != "" || flags.Secret != "") {
+		return fmt.Errorf("late and symbolic semantics do not support --por, --symmetry, --environment, --find or --secret")
+	}
+
+	switch command {
+	case CommandLts:
+		if (flags.Find != "" || flags.Secret != "") && reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve traces")
+		}
+	case CommandEquiv:
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve bisimilarity")
+		}
+		if flags.Late {
+			return fmt.Errorf("late input semantics does not preserve bisimilarity")
+		}
+		if flags.Symbolic && flags.Weak {
+			return fmt.Errorf("symbolic bisimilarity is only decided for strong bisimilarity")
+		}
+	case CommandTraces, CommandAccepts:
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve traces")
+		}
+		if flags.Late || flags.Symbolic {
+			return fmt.Errorf("late and symbolic semantics do not preserve traces")
+		}
+	case CommandCheck:
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve formulas")
+		}
+		if flags.Late || flags.Symbolic {
+			return fmt.Errorf("late and symbolic semantics do not preserve formulas")
+		}
+	case CommandLtl:
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve paths")
+		}
+		if flags.Late || flags.Symbolic {
+			return fmt.Errorf("late and symbolic semantics do not preserve paths")
+		}
+	case CommandSimulate, CommandStep:
+		if flags.Walks < 0 || flags.WalkLength < 0 {
+			return fmt.Errorf("number and length of walks must be positive")
+		}
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction are not simulated")
+		}
+		if flags.Late || flags.Symbolic {
+			return fmt.Errorf("late and symbolic semantics are not simulated")
+		}
+	case CommandMsc:
+		switch flags.Chart {
+		case "", ChartMermaid, ChartPlantUml:
+		default:
+			return fmt.Errorf("format must be mermaid or plantuml")
+		}
+		if reduced {
+			return fmt.Errorf("partial-order and symmetry reduction do not preserve traces")
+		}
+		if flags.Late || flags.Symbolic {
+			return fmt.Errorf("late and symbolic semantics do not preserve traces")
+		}
+	}
+	return nil
+}
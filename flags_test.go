@@ -0,0 +1,70 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestValidateFlags(t *testing.T) {
+	tests := map[string]struct {
+		flags   Flags
+		command string
+		valid   bool
+	}{
+		"minimise":            {Flags{Minimise: EquivWeak}, CommandLts, true},
+		"unknown_minimise":    {Flags{Minimise: "trace"}, CommandLts, false},
+		"symmetry_equiv":      {Flags{Symmetry: true}, CommandEquiv, false},
+		"symmetry_lts":        {Flags{Symmetry: true}, CommandLts, true},
+		"symmetry_traces":     {Flags{Symmetry: true}, CommandTraces, false},
+		"find_por":            {Flags{Find: "_BAD", PartialOrder: true}, CommandLts, false},
+		"find_unmarked":       {Flags{Find: "a"}, CommandLts, false},
+		"por_check":           {Flags{PartialOrder: true}, CommandCheck, false},
+		"unknown_fairness":    {Flags{Fairness: "unconditional"}, CommandLtl, false},
+		"por_ltl":             {Flags{PartialOrder: true}, CommandLtl, false},
+		"secret_symmetry":     {Flags{Secret: "s", Symmetry: true}, CommandLts, false},
+		"environment_lts":     {Flags{Environment: EnvironmentPublic}, CommandLts, true},
+		"environment_por":     {Flags{Environment: EnvironmentPublic, PartialOrder: true}, CommandLts, false},
+		"environment_unknown": {Flags{Environment: "private"}, CommandLts, false},
+		"observe_open":        {Flags{Observe: true}, CommandLts, false},
+		"observe_closed":      {Flags{Observe: true, Closed: true}, CommandLts, true},
+		"late_find":           {Flags{Find: "_BAD"}, CommandLate, false},
+		"late_secret":         {Flags{Secret: "s"}, CommandLate, false},
+		"late_por":            {Flags{PartialOrder: true}, CommandLate, false},
+		"late_symmetry":       {Flags{Symmetry: true}, CommandLate, false},
+		"late_environment":    {Flags{Environment: EnvironmentOutputs}, CommandLate, false},
+		"late_equiv":          {Flags{Late: true}, CommandEquiv, false},
+		"late":                {Flags{}, CommandLate, true},
+		"late_symbolic":       {Flags{Symbolic: true}, CommandLate, false},
+		"symbolic_weak":       {Flags{Symbolic: true, Weak: true}, CommandEquiv, false},
+		"symbolic_strong":     {Flags{Symbolic: true}, CommandEquiv, true},
+		"symbolic_lts":        {Flags{Symbolic: true}, CommandLts, true},
+		"symbolic_find":       {Flags{Symbolic: true, Find: "_BAD"}, CommandLts, false},
+		"symbolic_check":      {Flags{Symbolic: true}, CommandCheck, false},
+		"evict_unlimited":     {Flags{Evict: true}, CommandLts, false},
+		"evict_bounded":       {Flags{Evict: true, RegisterSize: 2}, CommandLts, true},
+		"simulate_walks":      {Flags{Walks: -1}, CommandSimulate, false},
+		"simulate_por":        {Flags{PartialOrder: true}, CommandSimulate, false},
+		"simulate_symbolic":   {Flags{Symbolic: true}, CommandSimulate, false},
+		"simulate":            {Flags{Walks: 10, WalkLength: 5}, CommandSimulate, true},
+		"step_symmetry":       {Flags{Symmetry: true}, CommandStep, false},
+		"step_late":           {Flags{Late: true}, CommandStep, false},
+		"step":                {Flags{}, CommandStep, true},
+		"accepts_por":         {Flags{PartialOrder: true}, CommandAccepts, false},
+		"accepts_symbolic":    {Flags{Symbolic: true}, CommandAccepts, false},
+		"accepts":             {Flags{Closed: true}, CommandAccepts, true},
+		"explain_late":        {Flags{Explain: true}, CommandLate, false},
+		"explain_lts_late":    {Flags{Explain: true, Late: true}, CommandLts, false},
+		"explain":             {Flags{Explain: true}, CommandLts, true},
+		"msc_format":          {Flags{Chart: "svg"}, CommandMsc, false},
+		"msc_plantuml":        {Flags{Chart: ChartPlantUml}, CommandMsc, true},
+		"msc_symmetry":        {Flags{Symmetry: true}, CommandMsc, false},
+		"msc_late":            {Flags{Late: true}, CommandMsc, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateFlags(test.flags, test.command)
+			if (err == nil) != test.valid {
+				t.Errorf("got error %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
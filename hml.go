@@ -0,0 +1,166 @@
+package pifra
+
+import (
+	"strings"
+)
+
+type hmlType int
+
+const (
+	hmlTrue hmlType = iota
+	hmlNot
+	hmlAnd
+	hmlDiamond
+)
+
+// hmlFormula is a Hennessy–Milner logic formula over FRA labels. The labels of
+// a distinguishing formula refer to the registers of the left LTS.
+type hmlFormula struct {
+	Type  hmlType
+	Label Label
+	// Whether the diamond is weak, i.e., allows τ-transitions before and after
+	// the labelled transition, or none for a τ label.
+	Weak bool
+	Subs []hmlFormula
+}
+
+func hmlNegate(formula hmlFormula) hmlFormula {
+	if formula.Type == hmlNot {
+		return formula.Subs[0]
+	}
+	return hmlFormula{
+		Type: hmlNot,
+		Subs: []hmlFormula{formula},
+	}
+}
+
+// hmlConjoin returns the conjunction of the formulas, without duplicates and
+// trivially true conjuncts.
+func hmlConjoin(formulas []hmlFormula) hmlFormula {
+	var subs []hmlFormula
+	seen := make(map[string]bool)
+	for _, formula := range formulas {
+		str := prettyPrintHml(formula)
+		if formula.Type == hmlTrue || seen[str] {
+			continue
+		}
+		seen[str] = true
+		subs = append(subs, formula)
+	}
+	switch len(subs) {
+	case 0:
+		return hmlFormula{
+			Type: hmlTrue,
+		}
+	case 1:
+		return subs[0]
+	}
+	return hmlFormula{
+		Type: hmlAnd,
+		Subs: subs,
+	}
+}
+
+// getFormula returns a formula which the left state of a removed pair
+// satisfies and the right state does not.
+func (c *bisimChecker) getFormula(id int, rank []int, witness []int, formulas map[int]hmlFormula) hmlFormula {
+	if formula, ok := formulas[id]; ok {
+		return formula
+	}
+
+	pair := c.pairs[id]
+	move := c.moves[id][witness[id]]
+	var subs []hmlFormula
+	for _, match := range move.Matches {
+		sub := c.getFormula(match.Pair, rank, witness, formulas)
+		if move.Side == 1 {
+			sub = hmlNegate(sub)
+		}
+		subs = append(subs, sub)
+	}
+
+	label := move.Transition.Label
+	if move.Side == 1 {
+		label = c.getLeftLabel(pair, move)
+	}
+	formula := hmlFormula{
+		Type:  hmlDiamond,
+		Label: label,
+		Weak:  c.weak,
+		Subs:  []hmlFormula{hmlConjoin(subs)},
+	}
+	if move.Side == 1 {
+		formula = hmlNegate(formula)
+	}
+	formulas[id] = formula
+	return formula
+}
+
+// getLeftLabel returns the label of a challenge of the right state of a pair
+// in terms of the registers of the left state, so that every transition of
+// the left state with the label matches it. Names unknown to the left state
+// are given labels not in its register, and a fresh name keeps its label.
+func (c *bisimChecker) getLeftLabel(pair bisimPair, move bisimMove) Label {
+	label := move.Transition.Label
+	if label.Symbol.Type == SymbolTypTau {
+		return label
+	}
+
+	inv := invertBijection(pair.Bijection)
+	used := make(map[int]bool)
+	for l := range c.lts[0].States[pair.Left].Registers.Registers {
+		used[l] = true
+	}
+	// Labels not in the register, given to names unknown to the left state.
+	unused := func(right int) int {
+		if l, ok := inv[right]; ok {
+			return l
+		}
+		l := 1
+		for used[l] {
+			l++
+		}
+		used[l] = true
+		inv[right] = l
+		return l
+	}
+
+	label.Symbol.Value = unused(label.Symbol.Value)
+	switch label.Symbol2.Type {
+	case SymbolTypKnown:
+		if _, ok := inv[label.Symbol2.Value]; !ok && label.Symbol.Type == SymbolTypInput {
+			// A name known only to the right state is fresh to the left.
+			label.Symbol2.Type = SymbolTypFreshInput
+		}
+		label.Symbol2.Value = unused(label.Symbol2.Value)
+	case SymbolTypFreshInput:
+		if move.OtherKnown != 0 {
+			label.Symbol2.Type = SymbolTypKnown
+			label.Symbol2.Value = move.OtherKnown
+		}
+	}
+	return label
+}
+
+// prettyPrintHml returns a formula with labels in the notation of
+// PrettyPrintLabel, e.g., <1 2*>(<2'2>tt & !<t>tt), where weak diamonds are
+// written <<l>>.
+func prettyPrintHml(formula hmlFormula) string {
+	switch formula.Type {
+	case hmlNot:
+		return "!" + prettyPrintHml(formula.Subs[0])
+	case hmlAnd:
+		var strs []string
+		for _, sub := range formula.Subs {
+			strs = append(strs, prettyPrintHml(sub))
+		}
+		return "(" + strings.Join(strs, " & ") + ")"
+	case hmlDiamond:
+		label := strings.TrimSpace(PrettyPrintLabel(formula.Label))
+		if formula.Weak {
+			return "<<" + label + ">>" + prettyPrintHml(formula.Subs[0])
+		}
+		return "<" + label + ">" + prettyPrintHml(formula.Subs[0])
+	}
+	return "tt"
+}
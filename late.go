@@ -0,0 +1,519 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+)
+
+// lateInputs explores inputs with late semantics: a placeholder for the name
+// received, instantiated when it is used.
+var lateInputs bool
+
+// placeholderPrefix prefixes the placeholder stored at a label, e.g., ?2.
+const placeholderPrefix = "?"
+
+// Names given by the correspondence check to the fresh names of an instance
+// and to the fresh name of a transition, unlike the names of normalisation.
+var (
+	instanceFreshPrefix = fnPrefix + "fresh"
+	transitionFreshName = fnPrefix + "new"
+)
+
+func isPlaceholder(name string) bool {
+	return strings.HasPrefix(name, placeholderPrefix)
+}
+
+// transLate returns the transitions of a state with late input semantics.
+func transLate(state Configuration) []Configuration {
+	if label := getUsedPlaceholder(state.Process); label != -1 {
+		return instantiatePlaceholder(state, label)
+	}
+	return storePlaceholders(trans(state))
+}
+
+// storePlaceholders replaces the inputs from the environment by an input of
+// a placeholder stored at the label of the fresh name.
+func storePlaceholders(tconfs []Configuration) []Configuration {
+	var confs []Configuration
+	for _, conf := range tconfs {
+		if conf.Label.Symbol.Type == SymbolTypInput {
+			if conf.Label.Symbol2.Type == SymbolTypKnown {
+				// Subsumed by the placeholder.
+				continue
+			}
+			conf = deepcopy.Copy(conf).(Configuration)
+			label := conf.Label.Symbol2.Value
+			placeholder := placeholderPrefix + strconv.Itoa(label)
+			subName(conf.Process, Name{
+				Name: conf.Registers.GetName(label),
+			}, Name{
+				Name: placeholder,
+			})
+			conf.Registers.Registers[label] = placeholder
+			conf.Label.Symbol2.Type = SymbolTypPlaceholder
+		}
+		confs = append(confs, conf)
+	}
+	return confs
+}
+
+// getUsedPlaceholder returns the least label of a placeholder used by a
+// process, or -1 if no placeholder is used.
+func getUsedPlaceholder(elem Element) int {
+	used := -1
+	use := func(name Name) {
+		if name.Type != Free || !isPlaceholder(name.Name) {
+			return
+		}
+		label, _ := strconv.Atoi(strings.TrimPrefix(name.Name, placeholderPrefix))
+		if used == -1 || label < used {
+			used = label
+		}
+	}
+
+	var walk func(elem Element)
+	walk = func(elem Element) {
+		switch elem.Type() {
+		case ElemTypOutput:
+			outElem := elem.(*ElemOutput)
+			use(outElem.Channel)
+			use(outElem.Output)
+		case ElemTypInput:
+			inpElem := elem.(*ElemInput)
+			use(inpElem.Channel)
+		case ElemTypMatch:
+			matchElem := elem.(*ElemEquality)
+			use(matchElem.NameL)
+			use(matchElem.NameR)
+		case ElemTypRestriction:
+			walk(elem.(*ElemRestriction).Next)
+		case ElemTypSum:
+			sumElem := elem.(*ElemSum)
+			walk(sumElem.ProcessL)
+			walk(sumElem.ProcessR)
+		case ElemTypParallel:
+			parElem := elem.(*ElemParallel)
+			walk(parElem.ProcessL)
+			walk(parElem.ProcessR)
+		case ElemTypProcess:
+			for _, param := range elem.(*ElemProcess).Parameters {
+				use(param)
+			}
+		case ElemTypRoot:
+			walk(elem.(*ElemRoot).Next)
+		}
+	}
+	walk(elem)
+	return used
+}
+
+// instantiatePlaceholder returns the configurations in which the placeholder
+// at a label is replaced by each other name of the register, or by a fresh
+// name stored at the label of the placeholder.
+func instantiatePlaceholder(state Configuration, label int) []Configuration {
+	placeholder := Name{
+		Name: state.Registers.GetName(label),
+	}
+	var confs []Configuration
+	for _, known := range state.Registers.Labels() {
+		if known == label {
+			continue
+		}
+		conf := deepcopy.Copy(state).(Configuration)
+		subName(conf.Process, placeholder, Name{
+			Name: conf.Registers.GetName(known),
+		})
+		delete(conf.Registers.Registers, label)
+		conf.Label = Label{
+			Symbol:  Symbol{Type: SymbolTypInstance, Value: label},
+			Symbol2: Symbol{Type: SymbolTypKnown, Value: known},
+		}
+		confs = append(confs, conf)
+	}
+
+	// The fresh name is renamed by normalisation.
+	conf := deepcopy.Copy(state).(Configuration)
+	freshName := bnPrefix + placeholder.Name
+	subName(conf.Process, placeholder, Name{
+		Name: freshName,
+	})
+	conf.Registers.Registers[label] = freshName
+	conf.Label = Label{
+		Symbol:  Symbol{Type: SymbolTypInstance, Value: label},
+		Symbol2: Symbol{Type: SymbolTypFreshInput, Value: label},
+	}
+	return append(confs, conf)
+}
+
+// lateMismatch is an instance of a state of the late LTS whose early
+// transitions differ from its late transitions.
+type lateMismatch struct {
+	State    int
+	Instance string
+	// The transitions of only one semantics, by names rather than labels.
+	Early []string
+	Late  []string
+}
+
+// checkLateCorrespondence checks the late LTS against the early semantics of
+// every instance of its placeholders, returning the number of instances
+// checked and the mismatches.
+func checkLateCorrespondence(lts Lts) (int, []lateMismatch) {
+	trns := getTransitionsBySource(lts.Transitions)
+	var ids []int
+	for id := range lts.States {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var checked int
+	var mismatches []lateMismatch
+	for _, id := range ids {
+		if !lts.isExplored(id) {
+			continue
+		}
+		state := lts.States[id]
+		for _, assignment := range getPlaceholderAssignments(state) {
+			late, ok := getLateTransitions(lts, trns, id, assignment)
+			if !ok {
+				// An instantiation leads to a state which was not explored.
+				continue
+			}
+			early := getEarlyTransitions(instantiateConf(state, assignment))
+			checked++
+
+			mismatch := lateMismatch{
+				State:    id,
+				Instance: prettyPrintAssignment(state, assignment),
+			}
+			for trn := range early {
+				if !late[trn] {
+					mismatch.Early = append(mismatch.Early, trn)
+				}
+			}
+			for trn := range late {
+				if !early[trn] {
+					mismatch.Late = append(mismatch.Late, trn)
+				}
+			}
+			if mismatch.Early != nil || mismatch.Late != nil {
+				sort.Strings(mismatch.Early)
+				sort.Strings(mismatch.Late)
+				mismatches = append(mismatches, mismatch)
+			}
+		}
+	}
+	return checked, mismatches
+}
+
+// getPlaceholderAssignments returns the assignments of names to the
+// placeholders of a state, up to the choice of fresh names.
+func getPlaceholderAssignments(state Configuration) []map[string]string {
+	var placeholders []string
+	var names []string
+	for _, label := range state.Registers.Labels() {
+		name := state.Registers.GetName(label)
+		if isPlaceholder(name) {
+			placeholders = append(placeholders, name)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	var assignments []map[string]string
+	assignment := make(map[string]string)
+	var assign func(i int, fresh int)
+	assign = func(i int, fresh int) {
+		if i == len(placeholders) {
+			assignments = append(assignments, deepcopy.Copy(assignment).(map[string]string))
+			return
+		}
+		for _, name := range names {
+			assignment[placeholders[i]] = name
+			assign(i+1, fresh)
+		}
+		// A fresh name of a previous placeholder, or a new one.
+		for f := 1; f <= fresh; f++ {
+			assignment[placeholders[i]] = instanceFreshPrefix + strconv.Itoa(f)
+			assign(i+1, fresh)
+		}
+		assignment[placeholders[i]] = instanceFreshPrefix + strconv.Itoa(fresh+1)
+		assign(i+1, fresh+1)
+	}
+	assign(0, 0)
+	return assignments
+}
+
+// instantiateConf returns a normalised configuration with the names of a
+// configuration renamed simultaneously. Labels of the register left with the
+// same name are merged.
+func instantiateConf(conf Configuration, renaming map[string]string) Configuration {
+	conf = deepcopy.Copy(conf).(Configuration)
+	var oldNames []string
+	for oldName := range renaming {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+	for i, oldName := range oldNames {
+		subName(conf.Process, Name{
+			Name: oldName,
+		}, Name{
+			Name: "%" + strconv.Itoa(i),
+		})
+	}
+	for i, oldName := range oldNames {
+		subName(conf.Process, Name{
+			Name: "%" + strconv.Itoa(i),
+		}, Name{
+			Name: renaming[oldName],
+		})
+	}
+
+	seen := make(map[string]bool)
+	for _, label := range conf.Registers.Labels() {
+		name := conf.Registers.GetName(label)
+		if newName, ok := renaming[name]; ok {
+			name = newName
+		}
+		if seen[name] {
+			delete(conf.Registers.Registers, label)
+		} else {
+			seen[name] = true
+			conf.Registers.Registers[label] = name
+		}
+	}
+	applyStructrualCongruence(conf)
+	return conf
+}
+
+// getNamedKey returns the key of a configuration by the names of its
+// register, regardless of their labels, and its process up to the numbering of
+// bound names.
+func getNamedKey(conf Configuration) string {
+	var names []string
+	for _, name := range conf.Registers.Registers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "{" + strings.Join(names, ",") + "}" + getCanonicalProcess(conf)
+}
+
+// getCanonicalProcess returns a process of a normalised configuration up to
+// the numbering of its bound names and the scopes of its restrictions. The
+// bound names are numbered in the order of the sorted process, which depends
+// on their numbering, so they are renumbered and the process sorted until it
+// is stable, taking the least process if it cycles.
+func getCanonicalProcess(conf Configuration) string {
+	conf = deepcopy.Copy(conf).(Configuration)
+	index := make(map[string]int)
+	var procs []string
+	for {
+		proc := PrettyPrintAst(conf.Process)
+		if i, ok := index[proc]; ok {
+			least := procs[i]
+			for _, p := range procs[i:] {
+				if p < least {
+					least = p
+				}
+			}
+			return least
+		}
+		index[proc] = len(procs)
+		procs = append(procs, proc)
+		narrowRes(conf.Process)
+		normaliseBoundNames(conf)
+		sortSumPar(conf.Process)
+		scopeRes(conf.Process)
+		sortRes(conf.Process)
+	}
+}
+
+// narrowRes narrows the scope of each restriction of a process to the parallel
+// components in which its name appears.
+func narrowRes(elem Element) Element {
+	switch elem.Type() {
+	case ElemTypOutput:
+		outElem := elem.(*ElemOutput)
+		outElem.Next = narrowRes(outElem.Next)
+	case ElemTypInput:
+		inpElem := elem.(*ElemInput)
+		inpElem.Next = narrowRes(inpElem.Next)
+	case ElemTypMatch:
+		matchElem := elem.(*ElemEquality)
+		matchElem.Next = narrowRes(matchElem.Next)
+	case ElemTypRestriction:
+		resElem := elem.(*ElemRestriction)
+		resElem.Next = narrowRes(resElem.Next)
+		if resElem.Next.Type() != ElemTypParallel {
+			break
+		}
+		var in, out []Element
+		for _, child := range getPar(resElem.Next) {
+			if appearsIn(child, resElem.Restrict) {
+				in = append(in, child)
+			} else {
+				out = append(out, child)
+			}
+		}
+		if len(out) == 0 || len(in) == 0 {
+			break
+		}
+		resElem.Next = buildComponents(nil, in).(*ElemRoot).Next
+		return buildComponents(nil, append(out, resElem)).(*ElemRoot).Next
+	case ElemTypSum:
+		sumElem := elem.(*ElemSum)
+		sumElem.ProcessL = narrowRes(sumElem.ProcessL)
+		sumElem.ProcessR = narrowRes(sumElem.ProcessR)
+	case ElemTypParallel:
+		parElem := elem.(*ElemParallel)
+		parElem.ProcessL = narrowRes(parElem.ProcessL)
+		parElem.ProcessR = narrowRes(parElem.ProcessR)
+	case ElemTypRoot:
+		rootElem := elem.(*ElemRoot)
+		rootElem.Next = narrowRes(rootElem.Next)
+	}
+	return elem
+}
+
+// getNamedLabel returns a label by the names of the register of the source,
+// renamed, with the fresh name of the transition named #new.
+func getNamedLabel(label Label, reg Registers, renaming map[string]string) string {
+	if label.Symbol.Type == SymbolTypTau {
+		return "t"
+	}
+	getName := func(label int) string {
+		name := reg.GetName(label)
+		if newName, ok := renaming[name]; ok {
+			return newName
+		}
+		return name
+	}
+	str := getName(label.Symbol.Value)
+	if label.Symbol.Type == SymbolTypOutput {
+		str = str + "'"
+	} else {
+		str = str + " "
+	}
+	switch label.Symbol2.Type {
+	case SymbolTypFreshInput:
+		str = str + transitionFreshName + "*"
+	case SymbolTypFreshOutput:
+		str = str + transitionFreshName + "^"
+	default:
+		str = str + getName(label.Symbol2.Value)
+	}
+	return str
+}
+
+// getEarlyTransitions returns the early transitions of a normalised
+// configuration by names.
+func getEarlyTransitions(conf Configuration) map[string]bool {
+	confs := trans(conf)
+	if closedSystem {
+		confs = filterClosedConfs(confs)
+	}
+	trns := make(map[string]bool)
+	for _, dst := range confs {
+		renaming := make(map[string]string)
+		if dst.Label.Symbol2.Type == SymbolTypFreshInput || dst.Label.Symbol2.Type == SymbolTypFreshOutput {
+			renaming[dst.Registers.GetName(dst.Label.Symbol2.Value)] = transitionFreshName
+		}
+		label := getNamedLabel(dst.Label, conf.Registers, nil)
+		trns[label+" -> "+getNamedKey(instantiateConf(dst, renaming))] = true
+	}
+	return trns
+}
+
+// getLateTransitions returns the late transitions by names of an instance of
+// a state of a late LTS, given by renaming its placeholders. It returns false
+// if an instantiation leads to a state which was not explored.
+func getLateTransitions(lts Lts, trnsBySource map[int][]Transition, id int,
+	renaming map[string]string) (map[string]bool, bool) {
+	state := lts.States[id]
+	getName := func(label int) string {
+		name := state.Registers.GetName(label)
+		if newName, ok := renaming[name]; ok {
+			return newName
+		}
+		return name
+	}
+
+	trns := trnsBySource[id]
+	if len(trns) > 0 && trns[0].Label.Symbol.Type == SymbolTypInstance {
+		// Follow the instantiation consistent with the renaming.
+		label := trns[0].Label.Symbol.Value
+		name := getName(label)
+		for _, trn := range trns {
+			dstRenaming := deepcopy.Copy(renaming).(map[string]string)
+			delete(dstRenaming, state.Registers.GetName(label))
+			if trn.Label.Symbol2.Type == SymbolTypKnown {
+				if getName(trn.Label.Symbol2.Value) != name {
+					continue
+				}
+			} else {
+				consistent := true
+				for _, other := range state.Registers.Labels() {
+					if other != label && getName(other) == name {
+						consistent = false
+					}
+				}
+				if !consistent {
+					continue
+				}
+				// The fresh name keeps the name of the instance.
+				dst := lts.States[trn.Destination]
+				dstRenaming[dst.Registers.GetName(label)] = name
+			}
+			if !lts.isExplored(trn.Destination) {
+				return nil, false
+			}
+			return getLateTransitions(lts, trnsBySource, trn.Destination, dstRenaming)
+		}
+		// No instantiation is consistent with the renaming.
+		return map[string]bool{}, true
+	}
+
+	instance := instantiateConf(state, renaming)
+	lateTrns := make(map[string]bool)
+	for _, trn := range trns {
+		dst := lts.States[trn.Destination]
+		dstRenaming := deepcopy.Copy(renaming).(map[string]string)
+		if trn.Label.Symbol2.Type != SymbolTypPlaceholder {
+			if trn.Label.Symbol2.Type == SymbolTypFreshOutput {
+				dstRenaming[dst.Registers.GetName(trn.Label.Symbol2.Value)] = transitionFreshName
+			}
+			label := getNamedLabel(trn.Label, state.Registers, renaming)
+			lateTrns[label+" -> "+getNamedKey(instantiateConf(dst, dstRenaming))] = true
+			continue
+		}
+
+		// The placeholder stands for each name of the instance and a fresh
+		// name.
+		placeholder := dst.Registers.GetName(trn.Label.Symbol2.Value)
+		channel := getName(trn.Label.Symbol.Value)
+		for _, name := range instance.Registers.Registers {
+			dstRenaming[placeholder] = name
+			lateTrns[channel+" "+name+" -> "+getNamedKey(instantiateConf(dst, dstRenaming))] = true
+		}
+		dstRenaming[placeholder] = transitionFreshName
+		label := channel + " " + transitionFreshName + "*"
+		lateTrns[label+" -> "+getNamedKey(instantiateConf(dst, dstRenaming))] = true
+	}
+	return lateTrns, true
+}
+
+// prettyPrintAssignment returns the names assigned to the placeholders of a
+// state.
+func prettyPrintAssignment(state Configuration, assignment map[string]string) string {
+	var strs []string
+	for _, label := range state.Registers.Labels() {
+		name := state.Registers.GetName(label)
+		if isPlaceholder(name) {
+			strs = append(strs, name+"="+assignment[name])
+		}
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,97 @@
+package pifra
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLateInputs(t *testing.T) {
+	tests := map[string]struct {
+		input  []byte
+		states int
+		labels []string
+	}{
+		"unused_placeholder": {
+			input:  []byte(`a(x).0`),
+			states: 2,
+			labels: []string{"1 1?"},
+		},
+		"placeholder_channel": {
+			input:  []byte(`a(x).x'<a>.0`),
+			states: 5,
+			labels: []string{"1 2?", "2=1 ", "2=2*", "1'1 ", "2'1 "},
+		},
+		"instantiated_after_communication": {
+			input:  []byte(`a(x).$c.(c'<c>.0 | c(y).a'<x>.0)`),
+			states: 6,
+			labels: []string{"1 2?", "t   ", "2=1 ", "2=2*", "1'1 ", "1'2 "},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		lateInputs = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lateInputs = true
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lts.States) != test.states {
+				t.Errorf("got %d states, want %d", len(lts.States), test.states)
+			}
+			var labels []string
+			for _, trn := range lts.Transitions {
+				labels = append(labels, PrettyPrintLabel(trn.Label))
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("got labels %q, want %q", labels, test.labels)
+			}
+		})
+	}
+}
+
+func TestLateCorrespondence(t *testing.T) {
+	tests := map[string][]byte{
+		"extrusion":     []byte(`a(x).x'<b>.0 | $c.a'<c>.c(y).0`),
+		"match":         []byte(`a(x).b(y).[x=y]c'<x>.0`),
+		"mismatch":      []byte(`a(x).[x!=a]x(y).0`),
+		"recursion":     []byte(`P(a) = a(x).(x'<a>.0 | P(a))` + "\n" + `P(a)`),
+		"communication": []byte(`a(x).$c.(c'<x>.0 | c(y).y'<y>.0)`),
+	}
+	for _, file := range []string{"test/password-insecure.pi", "test/server3.pi"} {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests[filepath.Base(file)] = input
+	}
+	maxStatesExplored = 50
+	registerSize = 1073741824
+	defer func() {
+		lateInputs = false
+	}()
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			lateInputs = true
+			lts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checked, mismatches := checkLateCorrespondence(lts)
+			if checked == 0 {
+				t.Error("no instance checked")
+			}
+			for _, mismatch := range mismatches {
+				t.Errorf("s%d %s: early only %v, late only %v",
+					mismatch.State, mismatch.Instance, mismatch.Early, mismatch.Late)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ type lexer struct {
     data []byte
     p, pe, cs int
     ts, te, act int
+    line, lineStart, scanned int
 }
 
 func newLexer(data []byte) *lexer {
@@ -28,7 +29,7 @@ func newLexer(data []byte) *lexer {
         pe: len(data),
     }
     
-//line lex.go:32
+//line lex.go:33
 	{
 	 lex.cs = parser_start
 	 lex.ts = 0
@@ -36,7 +37,7 @@ func newLexer(data []byte) *lexer {
 	 lex.act = 0
 	}
 
-//line lex.rl:25
+//line lex.rl:26
     return lex
 }
 
@@ -45,7 +46,7 @@ func (lex *lexer) Lex(out *yySymType) int {
     tok := 0
 
     
-//line lex.go:49
+//line lex.go:50
 	{
 	if ( lex.p) == ( lex.pe) {
 		goto _test_eof
@@ -62,77 +63,77 @@ func (lex *lexer) Lex(out *yySymType) int {
 	}
 	goto st_out
 tr2:
-//line lex.rl:50
+//line lex.rl:51
  lex.te = ( lex.p)+1
 
 	goto st2
 tr3:
-//line lex.rl:45
+//line lex.rl:46
  lex.te = ( lex.p)+1
 { tok = EXCLAMATION; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr4:
-//line lex.rl:38
+//line lex.rl:39
  lex.te = ( lex.p)+1
 { tok = DOLLARSIGN; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr5:
-//line lex.rl:35
+//line lex.rl:36
  lex.te = ( lex.p)+1
 { tok =  APOSTROPHE; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr6:
-//line lex.rl:40
+//line lex.rl:41
  lex.te = ( lex.p)+1
 { tok = LBRACKET; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr7:
-//line lex.rl:41
+//line lex.rl:42
  lex.te = ( lex.p)+1
 { tok = RBRACKET; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr8:
-//line lex.rl:39
+//line lex.rl:40
  lex.te = ( lex.p)+1
 { tok = PLUS; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr9:
-//line lex.rl:44
+//line lex.rl:45
  lex.te = ( lex.p)+1
 { tok = COMMA; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr10:
-//line lex.rl:48
+//line lex.rl:49
  lex.te = ( lex.p)+1
 { tok = DOT; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr12:
-//line lex.rl:42
+//line lex.rl:43
  lex.te = ( lex.p)+1
 { tok = LANGLE; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr13:
-//line lex.rl:46
+//line lex.rl:47
  lex.te = ( lex.p)+1
 { tok = EQUAL; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr14:
-//line lex.rl:43
+//line lex.rl:44
  lex.te = ( lex.p)+1
 { tok = RANGLE; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr15:
-//line lex.rl:36
+//line lex.rl:37
  lex.te = ( lex.p)+1
 { tok =  LSQBRACKET; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr16:
-//line lex.rl:37
+//line lex.rl:38
  lex.te = ( lex.p)+1
 { tok =  RSQBRACKET; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
 tr18:
-//line lex.rl:47
+//line lex.rl:48
  lex.te = ( lex.p)+1
 { tok = VERTBAR; {( lex.p)++;  lex.cs = 2; goto _out } }
 	goto st2
@@ -144,7 +145,7 @@ tr19:
  tok =  ZERO; {( lex.p)++;  lex.cs = 2; goto _out } }
 	case 16:
 	{( lex.p) = ( lex.te) - 1
- out.name = string(lex.data[lex.ts:lex.te]); tok = NAME; {( lex.p)++;  lex.cs = 2; goto _out } }
+ out.name = string(lex.data[lex.ts:lex.te]); out.pos = lex.position(lex.ts); tok = NAME; {( lex.p)++;  lex.cs = 2; goto _out } }
 	}
 	
 	goto st2
@@ -159,7 +160,7 @@ tr19:
 //line NONE:1
  lex.ts = ( lex.p)
 
-//line lex.go:163
+//line lex.go:164
 		switch  lex.data[( lex.p)] {
 		case 32:
 			goto tr2
@@ -222,14 +223,14 @@ tr0:
 //line NONE:1
  lex.te = ( lex.p)+1
 
-//line lex.rl:49
+//line lex.rl:50
  lex.act = 16;
 	goto st3
 tr11:
 //line NONE:1
  lex.te = ( lex.p)+1
 
-//line lex.rl:34
+//line lex.rl:35
  lex.act = 1;
 	goto st3
 	st3:
@@ -237,7 +238,7 @@ tr11:
 			goto _test_eof3
 		}
 	st_case_3:
-//line lex.go:241
+//line lex.go:242
 		switch {
 		case  lex.data[( lex.p)] < 65:
 			if 48 <=  lex.data[( lex.p)] &&  lex.data[( lex.p)] <= 57 {
@@ -285,7 +286,7 @@ tr11:
 	_out: {}
 	}
 
-//line lex.rl:53
+//line lex.rl:54
 
 
     return tok;
@@ -294,3 +295,18 @@ tr11:
 func (lex *lexer) Error(err string) {
     parseError = err
 }
+
+// position returns the position of an offset of the data, scanning it from
+// the offset of the previous token.
+func (lex *lexer) position(offset int) Position {
+    for ; lex.scanned < offset; lex.scanned++ {
+        if lex.data[lex.scanned] == '\n' {
+            lex.line++
+            lex.lineStart = lex.scanned + 1
+        }
+    }
+    return Position{
+        Line: lex.line + 1,
+        Column: offset - lex.lineStart + 1,
+    }
+}
@@ -14,6 +14,7 @@ type lexer struct {
     data []byte
     p, pe, cs int
     ts, te, act int
+    line, lineStart, scanned int
 }
 
 func newLexer(data []byte) *lexer {
@@ -46,7 +47,7 @@ func (lex *lexer) Lex(out *yySymType) int {
             '=' => { tok = EQUAL; fbreak; };
             '|' => { tok = VERTBAR; fbreak; };
             '.' => { tok = DOT; fbreak; };
-            [_]?[a-zA-Z0-9]+ => { out.name = string(lex.data[lex.ts:lex.te]); tok = NAME; fbreak; };
+            [_]?[a-zA-Z0-9]+ => { out.name = string(lex.data[lex.ts:lex.te]); out.pos = lex.position(lex.ts); tok = NAME; fbreak; };
             space;
         *|;
          write exec;
@@ -58,3 +59,18 @@ func (lex *lexer) Lex(out *yySymType) int {
 func (lex *lexer) Error(err string) {
     parseError = err
 }
+
+// position returns the position of an offset of the data, scanning it from
+// the offset of the previous token.
+func (lex *lexer) position(offset int) Position {
+    for ; lex.scanned < offset; lex.scanned++ {
+        if lex.data[lex.scanned] == '\n' {
+            lex.line++
+            lex.lineStart = lex.scanned + 1
+        }
+    }
+    return Position{
+        Line: lex.line + 1,
+        Column: offset - lex.lineStart + 1,
+    }
+}
@@ -0,0 +1,207 @@
+package pifra
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// errInterrupt is returned by readLine when the line is interrupted by ^C.
+var errInterrupt = errors.New("interrupt")
+
+// lineEditor reads lines with a history. On a terminal, the line is edited
+// in raw mode with the arrow keys, ^A, ^E, ^B, ^F, ^K, ^U and ^W, and the
+// history recalled with the up and down keys or ^P and ^N. Otherwise, lines
+// are read as they are.
+type lineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// rawMode puts the terminal into raw mode while a line is edited, and
+	// returns a function which restores its mode. It fails if the input is
+	// not a terminal.
+	rawMode func() (func(), error)
+	history []string
+	// Whether a line was edited in raw mode, i.e., the input is a terminal.
+	edited bool
+}
+
+// newLineEditor returns a line editor reading from in and echoing to out.
+func newLineEditor(in io.Reader, out io.Writer, rawMode func() (func(), error)) *lineEditor {
+	return &lineEditor{
+		in:      bufio.NewReader(in),
+		out:     out,
+		rawMode: rawMode,
+	}
+}
+
+// addHistory appends a line to the history, unless it is empty or repeats the
+// last line.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" ||
+		(len(e.history) != 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// readLine prints the prompt and returns the line read, without its line
+// ending. It returns io.EOF at the end of the input, or on ^D on an empty
+// line, and errInterrupt on ^C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if e.rawMode != nil {
+		if restore, err := e.rawMode(); err == nil {
+			defer restore()
+			e.edited = true
+			return e.editLine(prompt)
+		}
+	}
+	io.WriteString(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// editLine reads a line key by key from a terminal in raw mode, redrawing
+// the line after each key.
+func (e *lineEditor) editLine(prompt string) (string, error) {
+	io.WriteString(e.out, prompt)
+	var line []rune
+	var pos int
+	// The history entry being edited, where the last is the new line.
+	entry := len(e.history)
+	edits := append(append([]string{}, e.history...), "")
+	redraw := func() {
+		str := "\r" + prompt + string(line) + "\x1b[K"
+		if back := len(line) - pos; back > 0 {
+			str = str + "\x1b[" + strconv.Itoa(back) + "D"
+		}
+		io.WriteString(e.out, str)
+	}
+	recall := func(i int) {
+		if i < 0 || i >= len(edits) {
+			return
+		}
+		edits[entry] = string(line)
+		entry = i
+		line = []rune(edits[entry])
+		pos = len(line)
+		redraw()
+	}
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			if err == io.EOF && len(line) != 0 {
+				err = nil
+			}
+			return string(line), err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(line), nil
+		case 1: // ^A
+			pos = 0
+		case 2: // ^B
+			if pos > 0 {
+				pos--
+			}
+		case 3: // ^C
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupt
+		case 4: // ^D
+			if len(line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case 5: // ^E
+			pos = len(line)
+		case 6: // ^F
+			if pos < len(line) {
+				pos++
+			}
+		case 8, 127: // ^H, backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 11: // ^K
+			line = line[:pos]
+		case 14: // ^N
+			recall(entry + 1)
+		case 16: // ^P
+			recall(entry - 1)
+		case 21: // ^U
+			line = line[pos:]
+			pos = 0
+		case 23: // ^W
+			start := pos
+			for start > 0 && unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case 27: // Escape sequences of the arrow, home, end and delete keys.
+			seq := e.readEscape()
+			switch seq {
+			case "[A", "OA":
+				recall(entry - 1)
+			case "[B", "OB":
+				recall(entry + 1)
+			case "[C", "OC":
+				if pos < len(line) {
+					pos++
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+				}
+			case "[H", "OH", "[1~", "[7~":
+				pos = 0
+			case "[F", "OF", "[4~", "[8~":
+				pos = len(line)
+			case "[3~":
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+				pos++
+			}
+		}
+		redraw()
+	}
+}
+
+// readEscape reads the rest of an escape sequence after the escape character.
+func (e *lineEditor) readEscape() string {
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return string(seq)
+		}
+		seq = append(seq, r)
+		// The sequence ends with a letter or ~, after the introducer.
+		if len(seq) > 1 && (unicode.IsLetter(r) || r == '~') {
+			return string(seq)
+		}
+		if len(seq) == 1 && r != '[' && r != 'O' {
+			return string(seq)
+		}
+	}
+}
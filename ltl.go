@@ -0,0 +1,259 @@
+package pifra
+
+import (
+	"strings"
+)
+
+type ltlType int
+
+const (
+	ltlTrue ltlType = iota
+	ltlFalse
+	ltlProp
+	ltlNotProp
+	ltlNot
+	ltlAnd
+	ltlOr
+	ltlNext
+	ltlUntil
+	ltlRelease
+)
+
+// ltlFormula is a linear temporal logic formula over the labels of the
+// transitions of a path. An atomic proposition holds at a position of a path
+// if the label of the transition taken matches one of its patterns.
+type ltlFormula struct {
+	Type    ltlType
+	Actions []muAction
+	Subs    []ltlFormula
+}
+
+type ltlParser struct {
+	muParser
+}
+
+// parseLtlFormula parses a formula, e.g., G({1'_^} -> F{2 _?}), into negation
+// normal form with F and G replaced by U and R.
+func parseLtlFormula(input string) (ltlFormula, error) {
+	p := &ltlParser{
+		muParser: muParser{
+			input: input,
+		},
+	}
+	formula, err := p.parseImplication()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return ltlFormula{}, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return toLtlNegationNormalForm(formula, false), nil
+}
+
+func (p *ltlParser) parseImplication() (ltlFormula, error) {
+	left, err := p.parseDisjunction()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	if !p.accept("->") {
+		return left, nil
+	}
+	right, err := p.parseImplication()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	return ltlFormula{
+		Type: ltlOr,
+		Subs: []ltlFormula{{Type: ltlNot, Subs: []ltlFormula{left}}, right},
+	}, nil
+}
+
+func (p *ltlParser) parseDisjunction() (ltlFormula, error) {
+	left, err := p.parseConjunction()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	for p.accept("|") {
+		right, err := p.parseConjunction()
+		if err != nil {
+			return ltlFormula{}, err
+		}
+		left = ltlFormula{
+			Type: ltlOr,
+			Subs: []ltlFormula{left, right},
+		}
+	}
+	return left, nil
+}
+
+func (p *ltlParser) parseConjunction() (ltlFormula, error) {
+	left, err := p.parseBinary()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	for p.accept("&") {
+		right, err := p.parseBinary()
+		if err != nil {
+			return ltlFormula{}, err
+		}
+		left = ltlFormula{
+			Type: ltlAnd,
+			Subs: []ltlFormula{left, right},
+		}
+	}
+	return left, nil
+}
+
+func (p *ltlParser) parseBinary() (ltlFormula, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	typ := ltlUntil
+	if !p.accept("U") {
+		if !p.accept("R") {
+			return left, nil
+		}
+		typ = ltlRelease
+	}
+	right, err := p.parseBinary()
+	if err != nil {
+		return ltlFormula{}, err
+	}
+	return ltlFormula{
+		Type: typ,
+		Subs: []ltlFormula{left, right},
+	}, nil
+}
+
+func (p *ltlParser) parseUnary() (ltlFormula, error) {
+	p.skipSpace()
+	switch {
+	case p.acceptKeyword("true"):
+		return ltlFormula{Type: ltlTrue}, nil
+	case p.acceptKeyword("false"):
+		return ltlFormula{Type: ltlFalse}, nil
+	case p.accept("("):
+		formula, err := p.parseImplication()
+		if err != nil {
+			return ltlFormula{}, err
+		}
+		if !p.accept(")") {
+			return ltlFormula{}, p.errorf("expected )")
+		}
+		return formula, nil
+	case p.accept("{"):
+		end := strings.Index(p.input[p.pos:], "}")
+		if end == -1 {
+			return ltlFormula{}, p.errorf("expected }")
+		}
+		var actions []muAction
+		for _, pattern := range strings.Split(p.input[p.pos:p.pos+end], ",") {
+			action, err := parseMuAction(pattern)
+			if err != nil {
+				return ltlFormula{}, p.errorf("%s", err)
+			}
+			actions = append(actions, action)
+		}
+		p.pos = p.pos + end + 1
+		return ltlFormula{
+			Type:    ltlProp,
+			Actions: actions,
+		}, nil
+	}
+
+	for _, op := range []string{"!", "X", "F", "G"} {
+		if !p.accept(op) {
+			continue
+		}
+		sub, err := p.parseUnary()
+		if err != nil {
+			return ltlFormula{}, err
+		}
+		switch op {
+		case "!":
+			return ltlFormula{Type: ltlNot, Subs: []ltlFormula{sub}}, nil
+		case "X":
+			return ltlFormula{Type: ltlNext, Subs: []ltlFormula{sub}}, nil
+		case "F":
+			// F a = true U a.
+			return ltlFormula{Type: ltlUntil, Subs: []ltlFormula{{Type: ltlTrue}, sub}}, nil
+		}
+		// G a = false R a.
+		return ltlFormula{Type: ltlRelease, Subs: []ltlFormula{{Type: ltlFalse}, sub}}, nil
+	}
+
+	if p.pos == len(p.input) {
+		return ltlFormula{}, p.errorf("unexpected end of formula")
+	}
+	return ltlFormula{}, p.errorf("unexpected %q", p.input[p.pos:p.pos+1])
+}
+
+// toLtlNegationNormalForm pushes negations to the atomic propositions by the
+// dualities of the operators.
+func toLtlNegationNormalForm(formula ltlFormula, negated bool) ltlFormula {
+	dual := map[ltlType]ltlType{
+		ltlTrue:    ltlFalse,
+		ltlFalse:   ltlTrue,
+		ltlProp:    ltlNotProp,
+		ltlNotProp: ltlProp,
+		ltlAnd:     ltlOr,
+		ltlOr:      ltlAnd,
+		ltlNext:    ltlNext,
+		ltlUntil:   ltlRelease,
+		ltlRelease: ltlUntil,
+	}
+	if formula.Type == ltlNot {
+		return toLtlNegationNormalForm(formula.Subs[0], !negated)
+	}
+
+	nnf := formula
+	if negated {
+		nnf.Type = dual[formula.Type]
+	}
+	nnf.Subs = nil
+	for _, sub := range formula.Subs {
+		nnf.Subs = append(nnf.Subs, toLtlNegationNormalForm(sub, negated))
+	}
+	return nnf
+}
+
+// getLtlKey returns a string identifying a formula in negation normal form.
+func getLtlKey(formula ltlFormula) string {
+	switch formula.Type {
+	case ltlTrue:
+		return "true"
+	case ltlFalse:
+		return "false"
+	case ltlProp, ltlNotProp:
+		var strs []string
+		for _, action := range formula.Actions {
+			strs = append(strs, prettyPrintMuAction(action))
+		}
+		prop := "{" + strings.Join(strs, ",") + "}"
+		if formula.Type == ltlNotProp {
+			return "!" + prop
+		}
+		return prop
+	case ltlNext:
+		return "X" + getLtlKey(formula.Subs[0])
+	}
+	op := map[ltlType]string{
+		ltlAnd:     " & ",
+		ltlOr:      " | ",
+		ltlUntil:   " U ",
+		ltlRelease: " R ",
+	}[formula.Type]
+	return "(" + getLtlKey(formula.Subs[0]) + op + getLtlKey(formula.Subs[1]) + ")"
+}
+
+// matchLtlLiteral returns whether an atomic proposition or its negation holds
+// for a label, or for no label at a deadlock, where no proposition holds.
+func matchLtlLiteral(literal ltlFormula, label *Label) bool {
+	matched := label != nil && matchMuActions(literal.Actions, *label)
+	if literal.Type == ltlNotProp {
+		return !matched
+	}
+	return matched
+}
@@ -0,0 +1,164 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestCheckLtlFormula(t *testing.T) {
+	loop := []byte(`
+P(a) = a'<a>.P(a)
+Q(b) = b'<b>.0
+P(a) | Q(b)
+`)
+	intermittent := []byte(`
+A(a,c,d) = a'<a>.(c'<c>.A(a,c,d) + d'<d>.A(a,c,d))
+$c.(A(a,c,d) | c(x).b'<b>.0)
+`)
+	tests := map[string]struct {
+		input     []byte
+		formula   string
+		fairness  string
+		satisfied bool
+	}{
+		"eventually_unfair": {
+			input:   loop,
+			formula: `F{2'2}`,
+		},
+		"eventually_weakly_fair": {
+			input:     loop,
+			formula:   `F{2'2}`,
+			fairness:  FairnessWeak,
+			satisfied: true,
+		},
+		"infinitely_often": {
+			input:     loop,
+			formula:   `G F{1'1}`,
+			satisfied: true,
+		},
+		"globally": {
+			input:   loop,
+			formula: `G{1'1}`,
+		},
+		"no_tau": {
+			input:     loop,
+			formula:   `G !{t}`,
+			satisfied: true,
+		},
+		"next": {
+			input:     loop,
+			formula:   `{1'1} | X{1'1}`,
+			satisfied: true,
+		},
+		"until": {
+			input:     loop,
+			formula:   `{1'1} U {2'2}`,
+			fairness:  FairnessWeak,
+			satisfied: true,
+		},
+		"release": {
+			input:     loop,
+			formula:   `{2'2} R {1'1, 2'2}`,
+			satisfied: true,
+		},
+		"response": {
+			input: []byte(`
+P(a) = $c.a'<c>.c(x).a'<a>.P(a)
+P(a)
+`),
+			formula:   `G({1'_^} -> X F{2 _?})`,
+			satisfied: true,
+		},
+		"deadlock_idles": {
+			input:   []byte(`a'<a>.0`),
+			formula: `G F{1'1}`,
+		},
+		"deadlock_no_proposition": {
+			input:     []byte(`a'<a>.0`),
+			formula:   `X G !{-}`,
+			satisfied: true,
+		},
+		"weakly_fair_intermittent": {
+			input:    intermittent,
+			formula:  `F{2'2}`,
+			fairness: FairnessWeak,
+		},
+		"strongly_fair_intermittent": {
+			input:     intermittent,
+			formula:   `F{2'2}`,
+			fairness:  FairnessStrong,
+			satisfied: true,
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formula, err := parseLtlFormula(test.formula)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := checkLtlFormula(lts, formula, test.fairness)
+			if result.Satisfied != test.satisfied {
+				t.Fatalf("got satisfied %v, want %v", result.Satisfied, test.satisfied)
+			}
+			if result.Satisfied {
+				return
+			}
+
+			// The counterexample is a path from the root to a cycle.
+			steps := append(append([]ltlStep{}, result.Prefix...), result.Cycle...)
+			if len(result.Cycle) == 0 {
+				t.Fatal("empty cycle")
+			}
+			state := 0
+			for _, step := range steps {
+				if step.State != state {
+					t.Fatalf("step from s%d, want s%d", step.State, state)
+				}
+				if step.Transition != nil {
+					state = step.Transition.Destination
+				}
+			}
+			if state != result.Cycle[0].State {
+				t.Errorf("cycle ends at s%d, want s%d", state, result.Cycle[0].State)
+			}
+		})
+	}
+}
+
+func TestCheckLtlFormulaBounded(t *testing.T) {
+	maxStatesExplored = 2
+	registerSize = 1073741824
+	lts, err := generateLts([]byte(`a'<a>.a'<a>.a'<a>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	formula, err := parseLtlFormula(`F{t}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := checkLtlFormula(lts, formula, "")
+	if !result.Satisfied || !result.Bounded {
+		t.Errorf("got satisfied %v and bounded %v, want both", result.Satisfied, result.Bounded)
+	}
+}
+
+func TestParseLtlFormulaErrors(t *testing.T) {
+	for _, input := range []string{
+		`F`,
+		`{1'1`,
+		`{1 2 3}`,
+		`{1'1} U`,
+		`({1'1}`,
+		`G{1'1} {2'2}`,
+	} {
+		if _, err := parseLtlFormula(input); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+}
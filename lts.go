@@ -8,6 +8,7 @@ import (
 	stdlog "log"
 	"sort"
 	"strconv"
+	"strings"
 	"text/template"
 )
 
@@ -20,6 +21,35 @@ type Lts struct {
 	StatesExplored  int
 	StatesGenerated int
 
+	TransCacheHits   int
+	TransCacheMisses int
+
+	StatesPruned    int
+	OrbitsCollapsed int
+	StatesCollapsed int
+
+	NamesEvicted int
+
+	// States of the original LTS in each state of a minimised LTS.
+	Blocks map[int][]int
+
+	// The transition by which each state was first reached in the
+	// breadth-first exploration, from which a shortest trace to the state
+	// follows.
+	Parents map[int]Transition
+
+	// The derivations of each transition, when explaining transitions.
+	Provenance map[Transition][]Provenance
+
+	// The first transition on the name searched for, if any.
+	Found *Transition
+	// The first transition leaking a name of the secret restriction, if any.
+	Leaked *Transition
+
+	// The divergent component of each state in one, highlighted in the
+	// Graphviz output.
+	Divergent map[int]int
+
 	FreeNamesMap map[string]string
 }
 
@@ -39,6 +69,7 @@ type EdgeTemplate struct {
 	Source      string
 	Destination string
 	Label       string
+	Layout      string
 }
 
 var a4GVLayout = []byte(`
@@ -61,13 +92,49 @@ func explore(root Configuration) Lts {
 	states := make(map[int]Configuration)
 	// LTS transitions.
 	var trns []Transition
+	// The transition by which each state was first reached.
+	parents := make(map[int]Transition)
+	// The derivations of each transition.
+	var provenance map[Transition][]Provenance
+	if explainTransitions {
+		provenance = make(map[Transition][]Provenance)
+	}
+	// States pruned by partial-order reduction.
+	prunedKeys := make(map[string]bool)
+	// Register permutations from the canonical labels to the labels of each
+	// state, and the configurations collapsed into each state, when using
+	// symmetry reduction.
+	statePerms := make(map[int]map[int]int)
+	orbits := make(map[int]map[string]bool)
 	// State ID.
 	var stateId int
 
+	initTransCache()
+	defer func() {
+		transCache = nil
+	}()
+
+	addOrbitMember := func(id int, conf Configuration, perm map[int]int) {
+		if !symmetryReduction {
+			return
+		}
+		if _, ok := statePerms[id]; !ok {
+			invPerm := make(map[int]int)
+			for label, permLabel := range perm {
+				invPerm[permLabel] = label
+			}
+			statePerms[id] = invPerm
+			orbits[id] = make(map[string]bool)
+		}
+		orbits[id][getConfigurationKey(conf)] = true
+	}
+
 	applyStructrualCongruence(root)
-	rootKey := getConfigurationKey(root)
+	root = initKnowledge(root)
+	rootKey, rootPerm := getStateKey(root)
 	visited[rootKey] = stateId
 	states[stateId] = root
+	addOrbitMember(stateId, root, rootPerm)
 	stateId++
 
 	queue := list.New()
@@ -80,51 +147,146 @@ func explore(root Configuration) Lts {
 
 	var statesExplored int
 	var statesGenerated int
+	var found *Transition
+	var leaked *Transition
+	var namesEvicted int
 
 	// BFS traversal state exploration.
-	for queue.Len() > 0 && statesExplored < maxStatesExplored {
+	for queue.Len() > 0 && statesExplored < maxStatesExplored && found == nil && leaked == nil {
 		state := dequeue()
 
-		srcId := visited[getConfigurationKey(state)]
+		srcKey, _ := getStateKey(state)
+		srcId := visited[srcKey]
 
 		if len(state.Registers.Registers) > registerSize {
 			regSizeReached[srcId] = true
 		} else {
-			confs := trans(state)
+			var confs []Configuration
+			switch {
+			case symbolicNames:
+				confs = transSymbolic(state)
+			case lateInputs:
+				confs = transLate(state)
+			default:
+				confs = restrictEnvironment(state, trans(state))
+			}
+			if closedSystem {
+				confs = filterClosedConfs(confs)
+			}
+			if partialOrderReduction {
+				var pruned []string
+				confs, pruned = reducePartialOrder(state, confs, visited)
+				for _, key := range pruned {
+					prunedKeys[key] = true
+				}
+			}
 			for _, conf := range confs {
 				statesGenerated++
+				// The secret is recognised by its name, which is lost when an
+				// extruded name is normalised.
+				leak := isSecretLeak(conf)
 				applyStructrualCongruence(conf)
-				dstKey := getConfigurationKey(conf)
-				if _, ok := visited[dstKey]; !ok {
+				namesEvicted = namesEvicted + fitRegister(&conf)
+				updateKnowledge(state, &conf)
+				dstKey, dstPerm := getStateKey(conf)
+				_, seen := visited[dstKey]
+				if !seen {
 					visited[dstKey] = stateId
 					states[stateId] = conf
 					stateId++
 					queue.PushBack(conf)
 				}
+				dstId := visited[dstKey]
+				addOrbitMember(dstId, conf, dstPerm)
+
+				label := conf.Label
+				if symmetryReduction && (label.Symbol2.Type == SymbolTypFreshInput ||
+					label.Symbol2.Type == SymbolTypFreshOutput) {
+					// Fresh labels refer to the registers of the representative.
+					if permLabel, ok := dstPerm[label.Symbol2.Value]; ok {
+						label.Symbol2.Value = statePerms[dstId][permLabel]
+					}
+				}
 				trn := Transition{
 					Source:      srcId,
-					Destination: visited[dstKey],
-					Label:       conf.Label,
+					Destination: dstId,
+					Label:       label,
 				}
 				if !trnsSeen[trn] {
 					trnsSeen[trn] = true
 					trns = append(trns, trn)
 				}
+				if !seen {
+					parents[dstId] = trn
+				}
+				if provenance != nil {
+					addProvenance(provenance, trn, conf.Provenance)
+				}
+				// Stop at the first transition on the name searched for or
+				// leaking the secret, which is reached by a shortest trace,
+				// once its source state is explored.
+				if isFindLabel(state, label) && found == nil {
+					found = &trn
+				}
+				if leak && leaked == nil {
+					leaked = &trn
+				}
 			}
 		}
 
 		statesExplored++
 	}
 
+	var statesPruned int
+	for key := range prunedKeys {
+		if _, ok := visited[key]; !ok {
+			statesPruned++
+		}
+	}
+
+	var orbitsCollapsed int
+	var statesCollapsed int
+	for _, orbit := range orbits {
+		if len(orbit) > 1 {
+			orbitsCollapsed++
+			statesCollapsed = statesCollapsed + len(orbit) - 1
+		}
+	}
+
 	return Lts{
 		States:          states,
 		Transitions:     trns,
 		RegSizeReached:  regSizeReached,
 		StatesExplored:  statesExplored,
 		StatesGenerated: statesGenerated,
+
+		TransCacheHits:   transCacheHits,
+		TransCacheMisses: transCacheMisses,
+
+		StatesPruned:    statesPruned,
+		OrbitsCollapsed: orbitsCollapsed,
+		StatesCollapsed: statesCollapsed,
+
+		NamesEvicted: namesEvicted,
+
+		Parents:    parents,
+		Provenance: provenance,
+
+		Found:  found,
+		Leaked: leaked,
 	}
 }
 
+// getStateKey returns the key identifying a normalised configuration as a
+// state. With symmetry reduction, it also returns the register permutation
+// to the canonical labels of the key.
+func getStateKey(conf Configuration) (string, map[int]int) {
+	if symmetryReduction {
+		return getSymmetricKey(conf)
+	}
+	return getConfigurationKey(conf), nil
+}
+
 func init() {
 	RegisterGobs()
 }
@@ -171,6 +333,11 @@ func GenerateGraphVizFile(lts Lts, outputStateNo bool) []byte {
 	}
 	sort.Ints(ids)
 
+	var parents map[int]Transition
+	if outputPaths {
+		parents = getShortestPaths(lts)
+	}
+
 	for _, id := range ids {
 		conf := vertices[id]
 
@@ -188,6 +355,21 @@ func GenerateGraphVizFile(lts Lts, outputStateNo bool) []byte {
 		if lts.RegSizeReached[id] {
 			layout = layout + "peripheries=3,"
 		}
+		if _, ok := lts.Divergent[id]; ok {
+			layout = layout + "color=red,"
+		}
+
+		// The tooltip of a state is its block and the shortest trace to it.
+		var tooltips []string
+		if lts.Blocks != nil {
+			tooltips = append(tooltips, prettyPrintBlock(lts.Blocks[id]))
+		}
+		if outputPaths {
+			tooltips = append(tooltips, prettyPrintGraphPathLabels(getPath(parents, id)))
+		}
+		if tooltips != nil {
+			layout = layout + "tooltip=\"" + strings.Join(tooltips, "\\n") + "\","
+		}
 
 		vertex := VertexTemplate{
 			State:  "s" + strconv.Itoa(id),
@@ -207,7 +389,16 @@ func GenerateGraphVizFile(lts Lts, outputStateNo bool) []byte {
 			Destination: "s" + strconv.Itoa(edge.Destination),
 			Label:       PrettyPrintGraphLabel(edge.Label),
 		}
-		tmpl, _ := template.New("todos").Parse("    {{.Source}} -> {{.Destination}} [label=\"{{ .Label}}\"]\n")
+		if learnt := prettyPrintLearnt(lts, edge); learnt != "" {
+			edg.Label = edg.Label + " " + learnt
+		}
+		if isDivergentTransition(lts, edge) {
+			edg.Layout = "color=red,"
+		}
+		if lts.Provenance != nil {
+			edg.Layout = edg.Layout + "tooltip=\"" + prettyPrintProvenance(lts.Provenance[edge], "", "\\n") + "\","
+		}
+		tmpl, _ := template.New("todos").Parse("    {{.Source}} -> {{.Destination}} [{{.Layout}}label=\"{{ .Label}}\"]\n")
 		tmpl.Execute(&buffer, edg)
 	}
 
@@ -223,10 +414,14 @@ func (l Label) PrettyPrintGraph() string {
 }
 
 func PrettyPrintGraphLabel(label Label) string {
+	constraint := label.Constraint.prettyPrint("≠", ", ")
+	if constraint != "" {
+		constraint = constraint + " "
+	}
 	if label.Symbol.Type == SymbolTypTau {
-		return "τ"
+		return constraint + "τ"
 	}
-	return PrettyPrintGraphSymbol(label.Symbol) + PrettyPrintGraphSymbol(label.Symbol2)
+	return constraint + PrettyPrintGraphSymbol(label.Symbol) + PrettyPrintGraphSymbol(label.Symbol2)
 }
 
 func PrettyPrintGraphSymbol(symbol Symbol) string {
@@ -244,6 +439,10 @@ func PrettyPrintGraphSymbol(symbol Symbol) string {
 		return "τ"
 	case SymbolTypKnown:
 		return strconv.Itoa(s)
+	case SymbolTypPlaceholder:
+		return strconv.Itoa(s) + "?"
+	case SymbolTypInstance:
+		return strconv.Itoa(s) + "="
 	}
 	return ""
 }
@@ -292,6 +491,9 @@ func generateGraphVizTexFile(lts Lts, outputStateNo bool) []byte {
 		if lts.RegSizeReached[id] {
 			layout = layout + `style="thick",`
 		}
+		if _, ok := lts.Divergent[id]; ok {
+			layout = layout + `color="red",`
+		}
 
 		vertex := VertexTemplate{
 			State:  "s" + strconv.Itoa(id),
@@ -311,8 +513,14 @@ func generateGraphVizTexFile(lts Lts, outputStateNo bool) []byte {
 			Destination: "s" + strconv.Itoa(edge.Destination),
 			Label:       PrettyPrintTexGraphLabel(edge.Label),
 		}
+		if learnt := prettyPrintLearnt(lts, edge); learnt != "" {
+			edg.Label = edg.Label + ` \, ` + learnt
+		}
+		if isDivergentTransition(lts, edge) {
+			edg.Layout = `color="red",`
+		}
 		tmpl, _ := template.New("todos").Parse(
-			"    {{.Source}} -> {{.Destination}} [label=\"\",texlbl=\"${{.Label}}$\"]\n")
+			"    {{.Source}} -> {{.Destination}} [{{.Layout}}label=\"\",texlbl=\"${{.Label}}$\"]\n")
 		tmpl.Execute(&buffer, edg)
 	}
 
@@ -329,10 +537,14 @@ func PrettyPrintTexRegister(register Registers) string {
 	reg := register.Registers
 
 	for i, label := range labels {
+		left, right := "(", ")"
+		if register.Known[reg[label]] {
+			left, right = "[", "]"
+		}
 		if i == len(labels)-1 {
-			str = str + "(" + strconv.Itoa(label) + "," + GetTexName(reg[label]) + ")"
+			str = str + left + strconv.Itoa(label) + "," + GetTexName(reg[label]) + right
 		} else {
-			str = str + "(" + strconv.Itoa(label) + "," + GetTexName(reg[label]) + "),"
+			str = str + left + strconv.Itoa(label) + "," + GetTexName(reg[label]) + right + ","
 		}
 	}
 	return str + `\}`
@@ -419,10 +631,14 @@ func PrettyPrintTexAstAcc(elem Element, str string) string {
 }
 
 func PrettyPrintTexGraphLabel(label Label) string {
+	constraint := label.Constraint.prettyPrint(` \neq `, `, `)
+	if constraint != "" {
+		constraint = constraint + ` \, `
+	}
 	if label.Symbol.Type == SymbolTypTau {
-		return `\tau`
+		return constraint + `\tau`
 	}
-	return PrettyPrintTexGraphSymbol(label.Symbol) + ` \, ` + PrettyPrintTexGraphSymbol(label.Symbol2)
+	return constraint + PrettyPrintTexGraphSymbol(label.Symbol) + ` \, ` + PrettyPrintTexGraphSymbol(label.Symbol2)
 }
 
 func PrettyPrintTexGraphSymbol(symbol Symbol) string {
@@ -440,6 +656,10 @@ func PrettyPrintTexGraphSymbol(symbol Symbol) string {
 		return `\tau`
 	case SymbolTypKnown:
 		return strconv.Itoa(s)
+	case SymbolTypPlaceholder:
+		return strconv.Itoa(s) + `^{?}`
+	case SymbolTypInstance:
+		return strconv.Itoa(s) + ` =`
 	}
 	return ""
 }
@@ -480,10 +700,19 @@ func generatePrettyLts(lts Lts) []byte {
 		if lts.RegSizeReached[edge.Destination] {
 			dstR = "+"
 		}
+		label := PrettyPrintLabel(edge.Label)
+		if learnt := prettyPrintLearnt(lts, edge); learnt != "" {
+			// The label of a name learnt by the environment follows the label.
+			label = strings.TrimSpace(label) + " " + learnt
+		}
 		transString := "s" + strconv.Itoa(edge.Source) + srcR + "  " +
-			PrettyPrintLabel(edge.Label) + "  s" + strconv.Itoa(edge.Destination) + dstR + " = " +
+			label + "  s" + strconv.Itoa(edge.Destination) + dstR + " = " +
 			PrettyPrintRegister(vertex.Registers) + " |- " + PrettyPrintAst(vertex.Process)
 		buffer.WriteString(transString)
+		if lts.Provenance != nil {
+			// The derivations of the transition follow it, indented.
+			buffer.WriteString("\n" + prettyPrintProvenance(lts.Provenance[edge], "    ", "\n"))
+		}
 
 		// Prevent extraneous new line at last edge.
 		if i != len(edges)-1 {
@@ -491,11 +720,31 @@ func generatePrettyLts(lts Lts) []byte {
 		}
 	}
 
+	if lts.Blocks != nil {
+		buffer.WriteString("\n")
+		for id := 0; id < len(lts.Blocks); id++ {
+			buffer.WriteString("\ns" + strconv.Itoa(id) + " ~ " + prettyPrintBlock(lts.Blocks[id]))
+		}
+	}
+
+	if outputPaths {
+		buffer.WriteString("\n\n" + prettyPrintPaths(lts))
+	}
+
 	var output bytes.Buffer
 	buffer.WriteTo(&output)
 	return output.Bytes()
 }
 
+// prettyPrintBlock returns the states of a block of a minimised LTS.
+func prettyPrintBlock(block []int) string {
+	var states []string
+	for _, id := range block {
+		states = append(states, "s"+strconv.Itoa(id))
+	}
+	return "{" + strings.Join(states, ",") + "}"
+}
+
 // PrettyPrintConfiguration returns a pretty printed string of the configuration.
 func PrettyPrintConfiguration(conf Configuration) string {
 	return PrettyPrintLabel(conf.Label) + " -> " + PrettyPrintRegister(conf.Registers) + " ¦- " +
@@ -509,20 +758,29 @@ func PrettyPrintRegister(register Registers) string {
 	reg := register.Registers
 
 	for i, label := range labels {
+		// Names known to a restricted environment are in square brackets.
+		left, right := "(", ")"
+		if register.Known[reg[label]] {
+			left, right = "[", "]"
+		}
 		if i == len(labels)-1 {
-			str = str + "(" + strconv.Itoa(label) + "," + reg[label] + ")"
+			str = str + left + strconv.Itoa(label) + "," + reg[label] + right
 		} else {
-			str = str + "(" + strconv.Itoa(label) + "," + reg[label] + "),"
+			str = str + left + strconv.Itoa(label) + "," + reg[label] + right + ","
 		}
 	}
 	return str + "}"
 }
 
 func PrettyPrintLabel(label Label) string {
+	constraint := label.Constraint.prettyPrint("!=", ",")
+	if constraint != "" {
+		constraint = constraint + " "
+	}
 	if label.Symbol.Type == SymbolTypTau {
-		return "t   "
+		return constraint + "t   "
 	}
-	return PrettyPrintSymbol(label.Symbol) + PrettyPrintSymbol(label.Symbol2)
+	return constraint + PrettyPrintSymbol(label.Symbol) + PrettyPrintSymbol(label.Symbol2)
 }
 
 func PrettyPrintSymbol(symbol Symbol) string {
@@ -540,6 +798,10 @@ func PrettyPrintSymbol(symbol Symbol) string {
 		return "t   "
 	case SymbolTypKnown:
 		return strconv.Itoa(s) + " "
+	case SymbolTypPlaceholder:
+		return strconv.Itoa(s) + "?"
+	case SymbolTypInstance:
+		return strconv.Itoa(s) + "="
 	}
 	return ""
 }
@@ -0,0 +1,230 @@
+package pifra
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ChartMermaid  = "mermaid"
+	ChartPlantUml = "plantuml"
+)
+
+// chartEnvironment is the participant of a chart for the environment, and
+// chartRoot that for the components of the undeclared process, which are not
+// unfolded from a process definition. The other participants are the
+// instances of the definitions, e.g., Server#1.
+const (
+	chartEnvironment = "env"
+	chartRoot        = "main"
+)
+
+// prefixPattern matches an output or input prefix in the source of a model,
+// e.g., a'<b> or a(x).
+var prefixPattern = regexp.MustCompile(`^[_]?[a-zA-Z0-9]+\s*('\s*)?(<\s*[_]?[a-zA-Z0-9]+\s*>|\(\s*[_]?[a-zA-Z0-9]+\s*\))`)
+
+// chartStep is a transition of a trace, with its derivation and the text of
+// its label.
+type chartStep struct {
+	Label      Label
+	Provenance Provenance
+	Text       string
+}
+
+// chartMessage is a message of a chart between two participants.
+type chartMessage struct {
+	From string
+	To   string
+	Text string
+}
+
+// getPrefixText returns a prefix at a position of the source of a model, as
+// it is written without spaces.
+func getPrefixText(source []byte, position Position) string {
+	lines := bytes.Split(source, []byte("\n"))
+	if position.Line < 1 || position.Line > len(lines) ||
+		position.Column < 1 || position.Column > len(lines[position.Line-1]) {
+		return position.String()
+	}
+	prefix := prefixPattern.Find(lines[position.Line-1][position.Column-1:])
+	if prefix == nil {
+		return position.String()
+	}
+	return strings.Join(strings.Fields(string(prefix)), "")
+}
+
+// getParticipant returns the participant of a chart for the component of an
+// axiom.
+func getParticipant(axiom Provenance) string {
+	if axiom.Component == "" {
+		return chartRoot
+	}
+	return axiom.Component
+}
+
+// getChartMessages returns the messages of a trace: a communication between
+// components, written as the output prefix, or a label from or to the
+// environment. The instances of each definition are numbered from 1 in the
+// order they first take part.
+func getChartMessages(steps []chartStep, source []byte) []chartMessage {
+	var messages []chartMessage
+	for _, step := range steps {
+		var inputs []Provenance
+		var outputs []Provenance
+		for _, axiom := range step.Provenance.axioms() {
+			if axiom.Rule == RuleOut {
+				outputs = append(outputs, axiom)
+			} else {
+				inputs = append(inputs, axiom)
+			}
+		}
+		switch step.Label.Symbol.Type {
+		case SymbolTypTau:
+			if len(inputs) != 1 || len(outputs) != 1 {
+				continue
+			}
+			messages = append(messages, chartMessage{
+				From: getParticipant(outputs[0]),
+				To:   getParticipant(inputs[0]),
+				Text: getPrefixText(source, outputs[0].Position),
+			})
+		case SymbolTypOutput:
+			if len(outputs) != 1 {
+				continue
+			}
+			messages = append(messages, chartMessage{
+				From: getParticipant(outputs[0]),
+				To:   chartEnvironment,
+				Text: step.Text,
+			})
+		default:
+			if len(inputs) != 1 {
+				continue
+			}
+			messages = append(messages, chartMessage{
+				From: chartEnvironment,
+				To:   getParticipant(inputs[0]),
+				Text: step.Text,
+			})
+		}
+	}
+	numberInstances(messages)
+	return messages
+}
+
+// numberInstances renumbers the instances of the participants of messages
+// per process definition, in the order they first take part.
+func numberInstances(messages []chartMessage) {
+	instances := make(map[string]string)
+	counts := make(map[string]int)
+	number := func(participant string) string {
+		if participant == chartEnvironment || participant == chartRoot {
+			return participant
+		}
+		if _, ok := instances[participant]; !ok {
+			definition := getComponentDefinition(participant)
+			counts[definition]++
+			instances[participant] = definition + "#" + strconv.Itoa(counts[definition])
+		}
+		return instances[participant]
+	}
+	for i := range messages {
+		messages[i].From = number(messages[i].From)
+		messages[i].To = number(messages[i].To)
+	}
+}
+
+// getParticipants returns the participants of the messages of a chart, the
+// environment first and the components in the order they first take part.
+func getParticipants(messages []chartMessage) []string {
+	var participants []string
+	seen := make(map[string]bool)
+	for _, message := range messages {
+		for _, participant := range []string{message.From, message.To} {
+			if !seen[participant] {
+				seen[participant] = true
+				participants = append(participants, participant)
+			}
+		}
+	}
+	if seen[chartEnvironment] {
+		components := []string{chartEnvironment}
+		for _, participant := range participants {
+			if participant != chartEnvironment {
+				components = append(components, participant)
+			}
+		}
+		participants = components
+	}
+	return participants
+}
+
+// escapeMermaidText escapes the characters of a message which Mermaid
+// interprets.
+func escapeMermaidText(text string) string {
+	return strings.NewReplacer("#", "#35;", ";", "#59;", "<", "#lt;", ">", "#gt;").Replace(text)
+}
+
+// generateChart returns a message sequence chart of the messages as a
+// Mermaid sequence diagram or in PlantUML.
+func generateChart(messages []chartMessage, format string) string {
+	var lines []string
+	if format == ChartPlantUml {
+		lines = append(lines, "@startuml")
+		for _, participant := range getParticipants(messages) {
+			lines = append(lines, "participant "+strconv.Quote(participant))
+		}
+		// The names of the participants are quoted, as # starts a colour.
+		for _, message := range messages {
+			lines = append(lines, strconv.Quote(message.From)+" -> "+strconv.Quote(message.To)+" : "+message.Text)
+		}
+		lines = append(lines, "@enduml")
+	} else {
+		lines = append(lines, "sequenceDiagram")
+		for _, participant := range getParticipants(messages) {
+			lines = append(lines, "    participant "+participant)
+		}
+		for _, message := range messages {
+			lines = append(lines, "    "+message.From+"->>"+message.To+": "+escapeMermaidText(message.Text))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getPathChartSteps returns the steps of a path of an LTS, with the first of
+// the derivations of each transition and the names of its label.
+func getPathChartSteps(lts Lts, path []Transition) []chartStep {
+	trace := getNamedTrace(lts, path)
+	steps := make([]chartStep, len(path))
+	for i, trn := range path {
+		var provenance Provenance
+		for j, p := range lts.Provenance[trn] {
+			if j == 0 || p.String() < provenance.String() {
+				provenance = p
+			}
+		}
+		steps[i] = chartStep{
+			Label:      trn.Label,
+			Provenance: provenance,
+			Text:       trace[i],
+		}
+	}
+	return steps
+}
+
+// getTraceChartSteps returns the steps of a trace accepted by a model, with
+// the labels of the trace.
+func getTraceChartSteps(result acceptResult, labels []traceLabel) []chartStep {
+	item := result.Items[0]
+	steps := make([]chartStep, len(item.Path))
+	for i, trn := range item.Path {
+		steps[i] = chartStep{
+			Label:      trn.Label,
+			Provenance: item.Derivations[i],
+			Text:       labels[i].Text,
+		}
+	}
+	return steps
+}
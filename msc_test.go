@@ -0,0 +1,171 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComponentTags(t *testing.T) {
+	proc, err := InitProgram([]byte(`a'<a>.0 | a'<a>.0 | a'<a>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := []string{"Q", "P", ""}
+	i := 0
+	walkPrefixes(proc, func(position Position, component *string) {
+		*component = tags[i]
+		i++
+	})
+	explainTransitions = true
+	defer func() {
+		explainTransitions = false
+	}()
+	// Equal components are ordered by their tags, which are kept.
+	if key := getComponentKey(sortSumPar(proc)); key != ",P,Q" {
+		t.Errorf("got component tags %q, want %q", key, ",P,Q")
+	}
+
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	lts, err := generateLts([]byte(`P(a) = a(x).a'<a>.0
+P(c) | c(y).c'<c>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components := make(map[string]bool)
+	for _, trn := range lts.Transitions {
+		for _, p := range lts.Provenance[trn] {
+			for _, axiom := range p.axioms() {
+				components[getComponentDefinition(axiom.Component)] = true
+			}
+		}
+	}
+	if want := map[string]bool{"": true, "P": true}; !reflect.DeepEqual(components, want) {
+		t.Errorf("got components %v, want %v", components, want)
+	}
+}
+
+func TestMsc(t *testing.T) {
+	input := []byte(`Server(s) = s(c).c'<s>.Server(s)
+Client(s, r) = $c.s'<c>.c(x).r'<x>.0
+Server(srv) | Client(srv, out)`)
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	explainTransitions = true
+	defer func() {
+		explainTransitions = false
+	}()
+
+	proc, err := InitProgram(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, namesMap := newRootConf(proc)
+	labels, named, err := parseTrace("t\nt\nout'srv\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := checkAcceptance(root, namesMap, labels, named)
+	if result.Accepted != len(labels) {
+		t.Fatalf("got %d labels accepted, want %d", result.Accepted, len(labels))
+	}
+	messages := getChartMessages(getTraceChartSteps(result, labels), input)
+	want := []chartMessage{
+		{From: "Client#1", To: "Server#1", Text: "s'<c>"},
+		{From: "Server#1", To: "Client#1", Text: "c'<s>"},
+		{From: "Client#1", To: "env", Text: "out'srv"},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Fatalf("got messages %v, want %v", messages, want)
+	}
+
+	tests := map[string]string{
+		ChartMermaid: `sequenceDiagram
+    participant env
+    participant Client#1
+    participant Server#1
+    Client#1->>Server#1: s'#lt;c#gt;
+    Server#1->>Client#1: c'#lt;s#gt;
+    Client#1->>env: out'srv`,
+		ChartPlantUml: `@startuml
+participant "env"
+participant "Client#1"
+participant "Server#1"
+"Client#1" -> "Server#1" : s'<c>
+"Server#1" -> "Client#1" : c'<s>
+"Client#1" -> "env" : out'srv
+@enduml`,
+	}
+	for format, want := range tests {
+		t.Run(format, func(t *testing.T) {
+			if chart := generateChart(messages, format); chart != want {
+				t.Errorf("got chart\n%s\nwant\n%s", chart, want)
+			}
+		})
+	}
+}
+
+func TestMscPath(t *testing.T) {
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	explainTransitions = true
+	defer func() {
+		explainTransitions = false
+	}()
+	input := []byte(`P(a) = a(x).x'<a>.0
+P(c)`)
+	lts, err := generateLts(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The state reached by an input of the free name and its output.
+	for id := range lts.States {
+		path := getPath(getShortestPaths(lts), id)
+		if prettyPrintPathLabels(path) != "1 1, 1'1" {
+			continue
+		}
+		messages := getChartMessages(getPathChartSteps(lts, path), input)
+		want := []chartMessage{
+			{From: "env", To: "P#1", Text: "c c"},
+			{From: "P#1", To: "env", Text: "c'c"},
+		}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("got messages %v, want %v", messages, want)
+		}
+		return
+	}
+	t.Errorf("no state reached by 1 1, 1'1")
+}
+
+func TestMscInstances(t *testing.T) {
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	explainTransitions = true
+	defer func() {
+		explainTransitions = false
+	}()
+	input := []byte(`P(a) = a'<a>.a'<a>.0 + a(x).a(y).0
+P(b) | P(b)`)
+	lts, err := generateLts(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The instances of a definition are distinct participants, even when the
+	// transitions of equal components are reused.
+	for id := range lts.States {
+		path := getPath(getShortestPaths(lts), id)
+		if prettyPrintPathLabels(path) != "t, t" {
+			continue
+		}
+		messages := getChartMessages(getPathChartSteps(lts, path), input)
+		want := []chartMessage{
+			{From: "P#1", To: "P#2", Text: "a'<a>"},
+			{From: "P#2", To: "P#1", Text: "a'<a>"},
+		}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("got messages %v, want %v", messages, want)
+		}
+		return
+	}
+	t.Errorf("no state reached by t, t")
+}
@@ -0,0 +1,608 @@
+package pifra
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type muType int
+
+const (
+	muTrue muType = iota
+	muFalse
+	muVar
+	muNot
+	muAnd
+	muOr
+	muDiamond
+	muBox
+	muLeast
+	muGreatest
+)
+
+// muFormula is a modal mu-calculus formula over the labels of an LTS.
+type muFormula struct {
+	Type muType
+	// The variable of a fixpoint or a variable occurrence.
+	Var     string
+	Actions []muAction
+	Subs    []muFormula
+}
+
+// muRegAny is the register label of a pattern which matches any label.
+const muRegAny = 0
+
+type muMode int
+
+const (
+	muModeKnown muMode = iota
+	muModeFresh
+	muModeAny
+)
+
+// muAction is a pattern over labels in the notation of PrettyPrintLabel, e.g.,
+// 1'_^ matches the fresh outputs on the name at 1. Register labels may be the
+// wildcard _, and the mode ? matches both known and fresh names.
+type muAction struct {
+	Negated bool
+	// Whether the pattern matches any label, written -.
+	Any    bool
+	Type   SymbolType
+	Value  int
+	Value2 int
+	Mode   muMode
+}
+
+// matchMuActions returns whether a label matches any of the patterns of a
+// modality.
+func matchMuActions(actions []muAction, label Label) bool {
+	for _, action := range actions {
+		if matchMuAction(action, label) != action.Negated {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMuAction(action muAction, label Label) bool {
+	if action.Any {
+		return true
+	}
+	if action.Type != label.Symbol.Type {
+		return false
+	}
+	if action.Type == SymbolTypTau {
+		return true
+	}
+	if action.Value != muRegAny && action.Value != label.Symbol.Value {
+		return false
+	}
+	if action.Value2 != muRegAny && action.Value2 != label.Symbol2.Value {
+		return false
+	}
+	switch action.Mode {
+	case muModeKnown:
+		return label.Symbol2.Type == SymbolTypKnown
+	case muModeFresh:
+		return label.Symbol2.Type == SymbolTypFreshInput || label.Symbol2.Type == SymbolTypFreshOutput
+	}
+	return true
+}
+
+type muParser struct {
+	input string
+	pos   int
+}
+
+// parseMuFormula parses a formula, e.g., nu X.([1'_^]mu Y.(<1 2>tt | [-]Y) & [-]X),
+// into positive normal form.
+func parseMuFormula(input string) (muFormula, error) {
+	p := &muParser{
+		input: input,
+	}
+	formula, err := p.parseFormula()
+	if err != nil {
+		return muFormula{}, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return muFormula{}, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return toMuPositiveNormalForm(formula, false, make(map[string]bool))
+}
+
+func (p *muParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("formula:%d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *muParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes a token if it is next.
+func (p *muParser) accept(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos = p.pos + len(token)
+		return true
+	}
+	return false
+}
+
+// acceptKeyword consumes a keyword if it is next and not the prefix of an
+// identifier.
+func (p *muParser) acceptKeyword(keyword string) bool {
+	p.skipSpace()
+	end := p.pos + len(keyword)
+	if !strings.HasPrefix(p.input[p.pos:], keyword) ||
+		(end < len(p.input) && isMuIdentChar(p.input[end])) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *muParser) peekKeyword(keyword string) bool {
+	pos := p.pos
+	ok := p.acceptKeyword(keyword)
+	p.pos = pos
+	return ok
+}
+
+func isMuIdentChar(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+func (p *muParser) parseIdent() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && isMuIdentChar(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// parseFormula parses a fixpoint or a disjunction. The body of a fixpoint
+// extends as far to the right as possible.
+func (p *muParser) parseFormula() (muFormula, error) {
+	for _, fixpoint := range []struct {
+		keyword string
+		typ     muType
+	}{{"mu", muLeast}, {"nu", muGreatest}} {
+		if !p.acceptKeyword(fixpoint.keyword) {
+			continue
+		}
+		v := p.parseIdent()
+		if v == "" || !unicode.IsUpper(rune(v[0])) {
+			return muFormula{}, p.errorf("expected a variable starting with an upper-case letter")
+		}
+		if !p.accept(".") {
+			return muFormula{}, p.errorf("expected . after %s %s", fixpoint.keyword, v)
+		}
+		body, err := p.parseFormula()
+		if err != nil {
+			return muFormula{}, err
+		}
+		return muFormula{
+			Type: fixpoint.typ,
+			Var:  v,
+			Subs: []muFormula{body},
+		}, nil
+	}
+
+	left, err := p.parseConjunction()
+	if err != nil {
+		return muFormula{}, err
+	}
+	for p.accept("|") {
+		right, err := p.parseConjunction()
+		if err != nil {
+			return muFormula{}, err
+		}
+		left = muFormula{
+			Type: muOr,
+			Subs: []muFormula{left, right},
+		}
+	}
+	return left, nil
+}
+
+func (p *muParser) parseConjunction() (muFormula, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return muFormula{}, err
+	}
+	for p.accept("&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return muFormula{}, err
+		}
+		left = muFormula{
+			Type: muAnd,
+			Subs: []muFormula{left, right},
+		}
+	}
+	return left, nil
+}
+
+func (p *muParser) parseUnary() (muFormula, error) {
+	p.skipSpace()
+	switch {
+	case p.accept("!"):
+		sub, err := p.parseUnary()
+		if err != nil {
+			return muFormula{}, err
+		}
+		return muFormula{
+			Type: muNot,
+			Subs: []muFormula{sub},
+		}, nil
+	case p.accept("<"):
+		return p.parseModality(muDiamond, ">")
+	case p.accept("["):
+		return p.parseModality(muBox, "]")
+	case p.accept("("):
+		formula, err := p.parseFormula()
+		if err != nil {
+			return muFormula{}, err
+		}
+		if !p.accept(")") {
+			return muFormula{}, p.errorf("expected )")
+		}
+		return formula, nil
+	case p.acceptKeyword("tt"):
+		return muFormula{Type: muTrue}, nil
+	case p.acceptKeyword("ff"):
+		return muFormula{Type: muFalse}, nil
+	case p.peekKeyword("mu") || p.peekKeyword("nu"):
+		// A fixpoint as the operand of a conjunction, negation or modality.
+		return p.parseFormula()
+	}
+	if v := p.parseIdent(); v != "" {
+		if !unicode.IsUpper(rune(v[0])) {
+			return muFormula{}, p.errorf("unknown keyword %s", v)
+		}
+		return muFormula{
+			Type: muVar,
+			Var:  v,
+		}, nil
+	}
+	if p.pos == len(p.input) {
+		return muFormula{}, p.errorf("unexpected end of formula")
+	}
+	return muFormula{}, p.errorf("unexpected %q", p.input[p.pos:p.pos+1])
+}
+
+func (p *muParser) parseModality(typ muType, closing string) (muFormula, error) {
+	end := strings.Index(p.input[p.pos:], closing)
+	if end == -1 {
+		return muFormula{}, p.errorf("expected %s", closing)
+	}
+	var actions []muAction
+	for _, pattern := range strings.Split(p.input[p.pos:p.pos+end], ",") {
+		action, err := parseMuAction(pattern)
+		if err != nil {
+			return muFormula{}, p.errorf("%s", err)
+		}
+		actions = append(actions, action)
+	}
+	p.pos = p.pos + end + len(closing)
+
+	sub, err := p.parseUnary()
+	if err != nil {
+		return muFormula{}, err
+	}
+	return muFormula{
+		Type:    typ,
+		Actions: actions,
+		Subs:    []muFormula{sub},
+	}, nil
+}
+
+// parseMuAction parses a label pattern: - for any label, t for τ, a b for an
+// input and a'b for an output, where a and b are register labels or _, and b
+// is followed by * for a fresh input, ^ for a fresh output, or ? for any name.
+func parseMuAction(pattern string) (muAction, error) {
+	var action muAction
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasPrefix(pattern, "!") {
+		action.Negated = true
+		pattern = strings.TrimSpace(pattern[1:])
+	}
+	switch pattern {
+	case "-":
+		action.Any = true
+		return action, nil
+	case "t":
+		action.Type = SymbolTypTau
+		return action, nil
+	}
+
+	var channel, name string
+	if i := strings.Index(pattern, "'"); i != -1 {
+		action.Type = SymbolTypOutput
+		channel = strings.TrimSpace(pattern[:i])
+		name = strings.TrimSpace(pattern[i+1:])
+	} else {
+		fields := strings.Fields(pattern)
+		if len(fields) != 2 {
+			return muAction{}, fmt.Errorf("invalid label pattern %q", pattern)
+		}
+		action.Type = SymbolTypInput
+		channel = fields[0]
+		name = fields[1]
+	}
+	if name != "" {
+		switch name[len(name)-1] {
+		case '*':
+			if action.Type != SymbolTypInput {
+				return muAction{}, fmt.Errorf("fresh input pattern %q is an output", pattern)
+			}
+			action.Mode = muModeFresh
+			name = name[:len(name)-1]
+		case '^':
+			if action.Type != SymbolTypOutput {
+				return muAction{}, fmt.Errorf("fresh output pattern %q is an input", pattern)
+			}
+			action.Mode = muModeFresh
+			name = name[:len(name)-1]
+		case '?':
+			action.Mode = muModeAny
+			name = name[:len(name)-1]
+		}
+	}
+
+	var err error
+	if action.Value, err = parseMuRegister(channel); err != nil {
+		return muAction{}, fmt.Errorf("invalid label pattern %q", pattern)
+	}
+	if action.Value2, err = parseMuRegister(name); err != nil {
+		return muAction{}, fmt.Errorf("invalid label pattern %q", pattern)
+	}
+	return action, nil
+}
+
+func parseMuRegister(str string) (int, error) {
+	if str == "_" {
+		return muRegAny, nil
+	}
+	label, err := strconv.Atoi(str)
+	if err != nil || label < 1 {
+		return 0, fmt.Errorf("invalid register label %q", str)
+	}
+	return label, nil
+}
+
+// toMuPositiveNormalForm pushes negations to the variables, and returns an
+// error if a variable is free or under an odd number of negations.
+func toMuPositiveNormalForm(formula muFormula, negated bool, parity map[string]bool) (muFormula, error) {
+	dual := map[muType]muType{
+		muTrue:     muFalse,
+		muFalse:    muTrue,
+		muAnd:      muOr,
+		muOr:       muAnd,
+		muDiamond:  muBox,
+		muBox:      muDiamond,
+		muLeast:    muGreatest,
+		muGreatest: muLeast,
+	}
+
+	switch formula.Type {
+	case muNot:
+		return toMuPositiveNormalForm(formula.Subs[0], !negated, parity)
+	case muVar:
+		p, ok := parity[formula.Var]
+		if !ok {
+			return muFormula{}, fmt.Errorf("variable %s is not bound by a fixpoint", formula.Var)
+		}
+		if p != negated {
+			return muFormula{}, fmt.Errorf("variable %s occurs negated in its fixpoint", formula.Var)
+		}
+		return formula, nil
+	case muLeast, muGreatest:
+		inner := make(map[string]bool)
+		for v, p := range parity {
+			inner[v] = p
+		}
+		inner[formula.Var] = negated
+		parity = inner
+	}
+
+	pnf := formula
+	if negated {
+		pnf.Type = dual[formula.Type]
+	}
+	pnf.Subs = nil
+	for _, sub := range formula.Subs {
+		psub, err := toMuPositiveNormalForm(sub, negated, parity)
+		if err != nil {
+			return muFormula{}, err
+		}
+		pnf.Subs = append(pnf.Subs, psub)
+	}
+	return pnf, nil
+}
+
+type muResult int
+
+const (
+	muHolds muResult = iota
+	muFails
+	muUnknown
+)
+
+func (r muResult) String() string {
+	switch r {
+	case muHolds:
+		return "true"
+	case muFails:
+		return "false"
+	}
+	return "unknown"
+}
+
+// muChecker evaluates formulas on an LTS whose states may not all have been
+// explored. The transitions of an unexplored state are unknown, so each
+// formula is evaluated to the states which must satisfy it, for any
+// transitions of the unexplored states, and the states which may satisfy it.
+type muChecker struct {
+	trns      map[int][]Transition
+	explored  []bool
+	numStates int
+	// Fixpoint iterations, for the statistics.
+	iterations int
+}
+
+// checkMuFormula returns whether the root of an LTS satisfies a formula in
+// positive normal form, or unknown if this depends on the transitions of
+// unexplored states.
+func checkMuFormula(lts Lts, formula muFormula) (muResult, int) {
+	c := &muChecker{
+		trns:      getTransitionsBySource(lts.Transitions),
+		explored:  make([]bool, len(lts.States)),
+		numStates: len(lts.States),
+	}
+	for id := range c.explored {
+		c.explored[id] = lts.isExplored(id)
+	}
+
+	must := c.eval(formula, true, make(map[string][]bool))
+	if must[0] {
+		return muHolds, c.iterations
+	}
+	may := c.eval(formula, false, make(map[string][]bool))
+	if !may[0] {
+		return muFails, c.iterations
+	}
+	return muUnknown, c.iterations
+}
+
+// eval returns the states which must satisfy a formula, or the states which
+// may satisfy it, given the states of the variables.
+func (c *muChecker) eval(formula muFormula, must bool, env map[string][]bool) []bool {
+	states := make([]bool, c.numStates)
+	switch formula.Type {
+	case muTrue:
+		for id := range states {
+			states[id] = true
+		}
+	case muVar:
+		copy(states, env[formula.Var])
+	case muAnd, muOr:
+		left := c.eval(formula.Subs[0], must, env)
+		right := c.eval(formula.Subs[1], must, env)
+		for id := range states {
+			if formula.Type == muAnd {
+				states[id] = left[id] && right[id]
+			} else {
+				states[id] = left[id] || right[id]
+			}
+		}
+	case muDiamond, muBox:
+		sub := c.eval(formula.Subs[0], must, env)
+		// Whether the formula holds at some state, or at every state, which
+		// bounds it at the unknown destinations of an unexplored state.
+		var some, every = false, true
+		for _, ok := range sub {
+			some = some || ok
+			every = every && ok
+		}
+		for id := range states {
+			if !c.explored[id] {
+				if formula.Type == muDiamond {
+					states[id] = !must && some
+				} else {
+					states[id] = !must || every
+				}
+				continue
+			}
+			states[id] = formula.Type == muBox
+			for _, trn := range c.trns[id] {
+				if !matchMuActions(formula.Actions, trn.Label) {
+					continue
+				}
+				if formula.Type == muDiamond && sub[trn.Destination] {
+					states[id] = true
+					break
+				}
+				if formula.Type == muBox && !sub[trn.Destination] {
+					states[id] = false
+					break
+				}
+			}
+		}
+	case muLeast, muGreatest:
+		inner := make(map[string][]bool)
+		for v, s := range env {
+			inner[v] = s
+		}
+		approx := make([]bool, c.numStates)
+		if formula.Type == muGreatest {
+			for id := range approx {
+				approx[id] = true
+			}
+		}
+		for {
+			c.iterations++
+			inner[formula.Var] = approx
+			next := c.eval(formula.Subs[0], must, inner)
+			if equalStates(next, approx) {
+				break
+			}
+			approx = next
+		}
+		copy(states, approx)
+	}
+	return states
+}
+
+func equalStates(a []bool, b []bool) bool {
+	for id := range a {
+		if a[id] != b[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// prettyPrintMuAction returns a label pattern in the syntax of parseMuAction.
+func prettyPrintMuAction(action muAction) string {
+	var str string
+	if action.Negated {
+		str = "!"
+	}
+	if action.Any {
+		return str + "-"
+	}
+	if action.Type == SymbolTypTau {
+		return str + "t"
+	}
+	reg := func(label int) string {
+		if label == muRegAny {
+			return "_"
+		}
+		return strconv.Itoa(label)
+	}
+	str = str + reg(action.Value)
+	if action.Type == SymbolTypOutput {
+		str = str + "'"
+	} else {
+		str = str + " "
+	}
+	str = str + reg(action.Value2)
+	switch action.Mode {
+	case muModeFresh:
+		if action.Type == SymbolTypOutput {
+			str = str + "^"
+		} else {
+			str = str + "*"
+		}
+	case muModeAny:
+		str = str + "?"
+	}
+	return str
+}
@@ -0,0 +1,140 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestCheckMuFormula(t *testing.T) {
+	tests := map[string]struct {
+		input     []byte
+		formula   string
+		maxStates int
+		want      muResult
+	}{
+		"fresh_output_wildcard": {
+			input:     []byte(`$c.a'<c>.c(x).c'<x>.0`),
+			formula:   `<1'_^>tt`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"known_mode_does_not_match_fresh": {
+			input:     []byte(`$c.a'<c>.c(x).c'<x>.0`),
+			formula:   `<1'_>tt`,
+			maxStates: 100,
+			want:      muFails,
+		},
+		"fresh_input_after_fresh_output": {
+			input:     []byte(`$c.a'<c>.c(x).c'<x>.0`),
+			formula:   `[1'_^]<_ 2*>tt`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"any_mode": {
+			input:     []byte(`$c.a'<c>.c(x).c'<x>.0`),
+			formula:   `<-><1 _?><1'_?>[-]ff`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"negated_action": {
+			input:     []byte(`a'<a>.0 + b'<b>.0`),
+			formula:   `<!1'1>tt & [!_'_]ff & ![1'1, !_'_]ff`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"eventually_deadlocks": {
+			input:     []byte(`a'<a>.$c.(c'<c>.0 | c(x).0)`),
+			formula:   `mu X.([-]ff | <->X)`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"infinite_path": {
+			input: []byte(`
+P(a) = a'<a>.P(a)
+P(a)
+`),
+			formula:   `nu X.<1'1>X`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"negated_fixpoint": {
+			input: []byte(`
+P(a) = a'<a>.P(a)
+P(a)
+`),
+			formula:   `!mu X.[-]X`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"response": {
+			input: []byte(`
+P(a) = $c.a'<c>.c(x).a'<a>.P(a)
+P(a)
+`),
+			formula:   `nu X.([1'_^]mu Y.(<2 _?>tt | <->tt & [-]Y) & [-]X)`,
+			maxStates: 100,
+			want:      muHolds,
+		},
+		"unknown_beyond_frontier": {
+			input:     []byte(`a'<a>.a'<a>.a'<a>.0`),
+			formula:   `mu X.([-]ff | <->X)`,
+			maxStates: 2,
+			want:      muUnknown,
+		},
+		"diamond_within_frontier": {
+			input:     []byte(`a'<a>.a'<a>.a'<a>.0`),
+			formula:   `<-><->tt`,
+			maxStates: 2,
+			want:      muHolds,
+		},
+		"box_fails_within_frontier": {
+			input:     []byte(`a'<a>.a'<a>.a'<a>.0`),
+			formula:   `[-][-]ff`,
+			maxStates: 2,
+			want:      muFails,
+		},
+		"box_true_at_frontier": {
+			input:     []byte(`a'<a>.0`),
+			formula:   `[-]tt & !<->ff`,
+			maxStates: 0,
+			want:      muHolds,
+		},
+	}
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			maxStatesExplored = test.maxStates
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formula, err := parseMuFormula(test.formula)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := checkMuFormula(lts, formula); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMuFormulaErrors(t *testing.T) {
+	for _, input := range []string{
+		`X`,
+		`nu X.!X`,
+		`mu X.<->!X`,
+		`mu x.tt`,
+		`<1 2 3>tt`,
+		`<1'2*>tt`,
+		`<1 2^>tt`,
+		`<0'1>tt`,
+		`<1'1>`,
+		`(tt`,
+		`tt ff`,
+	} {
+		if _, err := parseMuFormula(input); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+}
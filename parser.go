@@ -36,6 +36,7 @@ var numParStack []int      // Saves the maximum curParLevel at different bracket
 type yySymType struct {
 	yys  int
 	name string
+	pos  Position
 }
 
 const NAME = 57346
@@ -523,7 +524,7 @@ yydefault:
 
 	case 6:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:72
+//line parser.y:73
 		{
 			// Reverse order of curProcParams
 			for i := len(curProcParams)/2 - 1; i >= 0; i-- {
@@ -542,19 +543,19 @@ yydefault:
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:91
+//line parser.y:92
 		{
 			curProcParams = append(curProcParams, yyDollar[1].name)
 		}
 	case 8:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:96
+//line parser.y:97
 		{
 			curProcParams = append(curProcParams, yyDollar[1].name)
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:102
+//line parser.y:103
 		{
 			name := yyDollar[1].name
 			DeclaredProcs[name] = DeclaredProcess{
@@ -567,21 +568,21 @@ yydefault:
 		}
 	case 10:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:115
+//line parser.y:116
 		{
 			undeclaredProcs = append(undeclaredProcs, curElem)
 			curElem = nil
 		}
 	case 22:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:145
+//line parser.y:146
 		{
 			Log("nil")
 			curElem = &ElemNil{}
 		}
 	case 23:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line parser.y:152
+//line parser.y:153
 		{
 			channel := yyDollar[1].name
 			output := yyDollar[4].name
@@ -592,7 +593,8 @@ yydefault:
 				Output: Name{
 					Name: output,
 				},
-				Next: curElem,
+				Next:     curElem,
+				Position: yyDollar[1].pos,
 			}
 			curElem = outputElem
 
@@ -600,7 +602,7 @@ yydefault:
 		}
 	case 24:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:170
+//line parser.y:172
 		{
 			channel := yyDollar[1].name
 			output := yyDollar[3].name
@@ -611,7 +613,8 @@ yydefault:
 				Output: Name{
 					Name: output,
 				},
-				Next: curElem,
+				Next:     curElem,
+				Position: yyDollar[1].pos,
 			}
 			curElem = outputElem
 
@@ -619,7 +622,7 @@ yydefault:
 		}
 	case 25:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:189
+//line parser.y:192
 		{
 			channel := yyDollar[1].name
 			input := yyDollar[3].name
@@ -630,7 +633,8 @@ yydefault:
 				Input: Name{
 					Name: input,
 				},
-				Next: curElem,
+				Next:     curElem,
+				Position: yyDollar[1].pos,
 			}
 			curElem = inputElem
 
@@ -638,7 +642,7 @@ yydefault:
 		}
 	case 26:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:208
+//line parser.y:212
 		{
 			equalityElem := &ElemEquality{
 				NameL: Name{
@@ -654,7 +658,7 @@ yydefault:
 		}
 	case 27:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line parser.y:224
+//line parser.y:228
 		{
 			equalityElem := &ElemEquality{
 				Inequality: true,
@@ -671,7 +675,7 @@ yydefault:
 		}
 	case 28:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:241
+//line parser.y:245
 		{
 			resElem := &ElemRestriction{
 				Restrict: Name{
@@ -684,7 +688,7 @@ yydefault:
 		}
 	case 29:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:254
+//line parser.y:258
 		{
 			// Track the maximum curSumLevel, i.e. no. of sums at this
 			// bracket level.
@@ -702,7 +706,7 @@ yydefault:
 		}
 	case 30:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:270
+//line parser.y:274
 		{
 			curSumLevel = curSumLevel - 1
 			if curSumLevel == 0 {
@@ -732,7 +736,7 @@ yydefault:
 		}
 	case 31:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:300
+//line parser.y:304
 		{
 			// Track the maximum curParLevel, i.e. no. of parallels at this
 			// bracket level.
@@ -750,7 +754,7 @@ yydefault:
 		}
 	case 32:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:316
+//line parser.y:320
 		{
 			curParLevel = curParLevel - 1
 			if curParLevel == 0 {
@@ -780,7 +784,7 @@ yydefault:
 		}
 	case 33:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:346
+//line parser.y:350
 		{
 			// Reverse order of curPconstNames
 			for i := len(curPconstNames)/2 - 1; i >= 0; i-- {
@@ -798,7 +802,7 @@ yydefault:
 		}
 	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:364
+//line parser.y:368
 		{
 			curPconstNames = append(curPconstNames, Name{
 				Name: yyDollar[1].name,
@@ -806,7 +810,7 @@ yydefault:
 		}
 	case 35:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:371
+//line parser.y:375
 		{
 			curPconstNames = append(curPconstNames, Name{
 				Name: yyDollar[1].name,
@@ -814,7 +818,7 @@ yydefault:
 		}
 	case 36:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:379
+//line parser.y:383
 		{
 			name := yyDollar[1].name
 			processElem := &ElemProcess{
@@ -825,7 +829,7 @@ yydefault:
 		}
 	case 37:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:390
+//line parser.y:394
 		{
 			// Sum elements:
 			// Save no. of sum on stack.
@@ -842,7 +846,7 @@ yydefault:
 		}
 	case 38:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:405
+//line parser.y:409
 		{
 			// Sum elements:
 			// Restore upper level no. of sums.
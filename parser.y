@@ -30,6 +30,7 @@ var numParStack []int         // Saves the maximum curParLevel at different brac
 
 %union {
    name string
+   pos Position
 }
 
 %token <name> NAME
@@ -160,6 +161,7 @@ output:
                 Name: output,
             },
             Next: curElem,
+            Position: $<pos>1,
         }
         curElem = outputElem
 
@@ -178,6 +180,7 @@ output:
                 Name: output,
             },
             Next: curElem,
+            Position: $<pos>1,
         }
         curElem = outputElem
 
@@ -197,6 +200,7 @@ input:
                 Name: input,
             },
             Next: curElem,
+            Position: $<pos>1,
         }
         curElem = inputElem
 
@@ -17,11 +17,8 @@ var log = false
 
 // InitProgram parses the byte array and returns the root undeclared process.
 func InitProgram(program []byte) (Element, error) {
-	initParser()
-	boundNameIndex = 0
-	lex := newLexer(program)
-	if code := yyParse(lex); code != 0 {
-		return nil, fmt.Errorf(parseError)
+	if err := parseProgram(program); err != nil {
+		return nil, err
 	}
 	if len(undeclaredProcs) == 0 {
 		return nil, fmt.Errorf("a process must be undeclared to initialise the program")
@@ -33,6 +30,17 @@ func InitProgram(program []byte) (Element, error) {
 	return root, nil
 }
 
+// parseProgram parses the byte array into the declared processes and the
+// undeclared processes, which may be none.
+func parseProgram(program []byte) error {
+	initParser()
+	lex := newLexer(program)
+	if code := yyParse(lex); code != 0 {
+		return fmt.Errorf(parseError)
+	}
+	return nil
+}
+
 // Log prints debug statements.
 func Log(strs ...string) {
 	if log {
@@ -43,6 +51,7 @@ func Log(strs ...string) {
 func initParser() {
 	DeclaredProcs = make(map[string]DeclaredProcess)
 	undeclaredProcs = []Element{}
+	boundNameIndex = 0
 }
 
 func popParStack() Element {
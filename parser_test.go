@@ -35,6 +35,7 @@ a'<b>.P
 					Output: Name{
 						Name: "b",
 					},
+					Position: Position{Line: 2, Column: 1},
 					Next: &ElemProcess{
 						Name: "P",
 					},
@@ -54,6 +55,7 @@ a(b).P
 					Input: Name{
 						Name: "b",
 					},
+					Position: Position{Line: 2, Column: 1},
 					Next: &ElemProcess{
 						Name: "P",
 					},
@@ -167,6 +169,7 @@ P = a'<b>.c(d).0
 						Output: Name{
 							Name: "b",
 						},
+						Position: Position{Line: 2, Column: 5},
 						Next: &ElemInput{
 							Channel: Name{
 								Name: "c",
@@ -174,7 +177,8 @@ P = a'<b>.c(d).0
 							Input: Name{
 								Name: "d",
 							},
-							Next: &ElemNil{},
+							Position: Position{Line: 2, Column: 11},
+							Next:     &ElemNil{},
 						},
 					},
 					Parameters: []string{},
@@ -224,6 +228,7 @@ i(j).k'<l>.0
 						Output: Name{
 							Name: "b",
 						},
+						Position: Position{Line: 2, Column: 5},
 						Next: &ElemInput{
 							Channel: Name{
 								Name: "c",
@@ -231,7 +236,8 @@ i(j).k'<l>.0
 							Input: Name{
 								Name: "d",
 							},
-							Next: &ElemNil{},
+							Position: Position{Line: 2, Column: 11},
+							Next:     &ElemNil{},
 						},
 					},
 					Parameters: []string{},
@@ -264,6 +270,7 @@ i(j).k'<l>.0
 					Input: Name{
 						Name: "j",
 					},
+					Position: Position{Line: 4, Column: 1},
 					Next: &ElemOutput{
 						Channel: Name{
 							Name: "k",
@@ -271,7 +278,8 @@ i(j).k'<l>.0
 						Output: Name{
 							Name: "l",
 						},
-						Next: &ElemNil{},
+						Position: Position{Line: 4, Column: 6},
+						Next:     &ElemNil{},
 					},
 				},
 			},
@@ -290,7 +298,8 @@ R(i,j,k) = a(b).0 | (c'<d>.0 | e'<f>.0) | g(h).P(a,b,c,d) | i(j).Proc1
 							Input: Name{
 								Name: "b",
 							},
-							Next: &ElemNil{},
+							Position: Position{Line: 2, Column: 12},
+							Next:     &ElemNil{},
 						},
 						ProcessR: &ElemParallel{
 							ProcessL: &ElemParallel{
@@ -301,7 +310,8 @@ R(i,j,k) = a(b).0 | (c'<d>.0 | e'<f>.0) | g(h).P(a,b,c,d) | i(j).Proc1
 									Output: Name{
 										Name: "d",
 									},
-									Next: &ElemNil{},
+									Position: Position{Line: 2, Column: 22},
+									Next:     &ElemNil{},
 								},
 								ProcessR: &ElemOutput{
 									Channel: Name{
@@ -310,7 +320,8 @@ R(i,j,k) = a(b).0 | (c'<d>.0 | e'<f>.0) | g(h).P(a,b,c,d) | i(j).Proc1
 									Output: Name{
 										Name: "f",
 									},
-									Next: &ElemNil{},
+									Position: Position{Line: 2, Column: 32},
+									Next:     &ElemNil{},
 								},
 							},
 							ProcessR: &ElemParallel{
@@ -321,6 +332,7 @@ R(i,j,k) = a(b).0 | (c'<d>.0 | e'<f>.0) | g(h).P(a,b,c,d) | i(j).Proc1
 									Input: Name{
 										Name: "h",
 									},
+									Position: Position{Line: 2, Column: 43},
 									Next: &ElemProcess{
 										Name: "P",
 										Parameters: []Name{
@@ -338,6 +350,7 @@ R(i,j,k) = a(b).0 | (c'<d>.0 | e'<f>.0) | g(h).P(a,b,c,d) | i(j).Proc1
 									Input: Name{
 										Name: "j",
 									},
+									Position: Position{Line: 2, Column: 61},
 									Next: &ElemProcess{
 										Name: "Proc1",
 									},
@@ -505,6 +518,7 @@ $a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 						Input: Name{
 							Name: "a",
 						},
+						Position: Position{Line: 2, Column: 4},
 						Next: &ElemRestriction{
 							Restrict: Name{
 								Name: "a",
@@ -517,7 +531,8 @@ $a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 									Output: Name{
 										Name: "a",
 									},
-									Next: &ElemNil{},
+									Position: Position{Line: 2, Column: 13},
+									Next:     &ElemNil{},
 								},
 								ProcessR: &ElemRestriction{
 									Restrict: Name{
@@ -531,7 +546,8 @@ $a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 											Input: Name{
 												Name: "b",
 											},
-											Next: &ElemNil{},
+											Position: Position{Line: 2, Column: 27},
+											Next:     &ElemNil{},
 										},
 										ProcessR: &ElemInput{
 											Channel: Name{
@@ -540,7 +556,8 @@ $a.b(a).$a.(b'<a>.0 | $b.(a(b).0 | c(d).0))
 											Input: Name{
 												Name: "d",
 											},
-											Next: &ElemNil{},
+											Position: Position{Line: 2, Column: 36},
+											Next:     &ElemNil{},
 										},
 									},
 								},
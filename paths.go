@@ -0,0 +1,151 @@
+package pifra
+
+import (
+	"bytes"
+	"encoding/json"
+	stdlog "log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// outputPaths adds a shortest trace from the root to each state to the
+// pretty-printed, JSON and Graphviz DOT outputs of the LTS.
+var outputPaths bool
+
+// jsonLts is an LTS in the JSON output.
+type jsonLts struct {
+	States      []jsonState      `json:"states"`
+	Transitions []jsonTransition `json:"transitions"`
+}
+
+// jsonState is a state of an LTS in the JSON output. The path is the labels
+// of a shortest trace from the root, if the paths are output.
+type jsonState struct {
+	Id             int            `json:"id"`
+	Registers      []jsonRegister `json:"registers"`
+	Process        string         `json:"process"`
+	RegSizeReached bool           `json:"regSizeReached,omitempty"`
+	Block          []int          `json:"block,omitempty"`
+	Path           *[]string      `json:"path,omitempty"`
+}
+
+// jsonRegister is a register of a state in the JSON output, and whether its
+// name is known to a restricted environment.
+type jsonRegister struct {
+	Label int    `json:"label"`
+	Name  string `json:"name"`
+	Known bool   `json:"known,omitempty"`
+}
+
+// jsonTransition is a transition of an LTS in the JSON output, with the label
+// in the pretty-printed format.
+type jsonTransition struct {
+	Source      int    `json:"source"`
+	Destination int    `json:"destination"`
+	Label       string `json:"label"`
+}
+
+// getPathLabels returns the labels of a path in the pretty-printed format.
+func getPathLabels(path []Transition) []string {
+	labels := make([]string, len(path))
+	for i, trn := range path {
+		labels[i] = strings.TrimSpace(PrettyPrintLabel(trn.Label))
+	}
+	return labels
+}
+
+// prettyPrintPathLabels returns the labels of a path separated by commas, in
+// the pretty-printed format.
+func prettyPrintPathLabels(path []Transition) string {
+	return strings.Join(getPathLabels(path), ", ")
+}
+
+// prettyPrintGraphPathLabels returns the labels of a path separated by
+// commas, in the format of the Graphviz DOT file, where the empty path is ε.
+func prettyPrintGraphPathLabels(path []Transition) string {
+	if len(path) == 0 {
+		return "ε"
+	}
+	labels := make([]string, len(path))
+	for i, trn := range path {
+		labels[i] = strings.TrimSpace(PrettyPrintGraphLabel(trn.Label))
+	}
+	return strings.Join(labels, ", ")
+}
+
+// prettyPrintPaths returns a line per state of the LTS with the labels of a
+// shortest trace from the root to it.
+func prettyPrintPaths(lts Lts) string {
+	parents := getShortestPaths(lts)
+	var ids []int
+	for id := range lts.States {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	lines := make([]string, len(ids))
+	for i, id := range ids {
+		lines[i] = strings.TrimSpace("s" + strconv.Itoa(id) + " : " +
+			prettyPrintPathLabels(getPath(parents, id)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateJsonLts returns the LTS encoded as JSON.
+func generateJsonLts(lts Lts) []byte {
+	var parents map[int]Transition
+	if outputPaths {
+		parents = getShortestPaths(lts)
+	}
+	var ids []int
+	for id := range lts.States {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	output := jsonLts{
+		States:      []jsonState{},
+		Transitions: []jsonTransition{},
+	}
+	for _, id := range ids {
+		conf := lts.States[id]
+		registers := []jsonRegister{}
+		for _, label := range conf.Registers.Labels() {
+			name := conf.Registers.Registers[label]
+			registers = append(registers, jsonRegister{
+				Label: label,
+				Name:  name,
+				Known: conf.Registers.Known[name],
+			})
+		}
+		state := jsonState{
+			Id:             id,
+			Registers:      registers,
+			Process:        PrettyPrintAst(conf.Process),
+			RegSizeReached: lts.RegSizeReached[id],
+			Block:          lts.Blocks[id],
+		}
+		if outputPaths {
+			labels := getPathLabels(getPath(parents, id))
+			state.Path = &labels
+		}
+		output.States = append(output.States, state)
+	}
+	for _, trn := range lts.Transitions {
+		output.Transitions = append(output.Transitions, jsonTransition{
+			Source:      trn.Source,
+			Destination: trn.Destination,
+			Label:       strings.TrimSpace(PrettyPrintLabel(trn.Label)),
+		})
+	}
+
+	// The processes are not escaped for HTML, e.g., their outputs a'<b>.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(output); err != nil {
+		stdlog.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,85 @@
+package pifra
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExploreParents(t *testing.T) {
+	tests := map[string][]byte{
+		"recursion": []byte(`P(a) = a(x).(x'<a>.P(a) | a'<x>.0)
+P(c)`),
+		"restriction": []byte(`$n.a'<n>.n(x).x'<x>.0`),
+		"sum":         []byte(`a(x).x'<x>.0 + a'<a>.a(y).0`),
+	}
+	maxStatesExplored = 20
+	registerSize = 1073741824
+	defer func() {
+		maxStatesExplored = 100
+	}()
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			lts, err := generateLts(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lts.Parents) != len(lts.States)-1 {
+				t.Errorf("got %d parents, want %d", len(lts.Parents), len(lts.States)-1)
+			}
+			// The parents of the exploration are those of a breadth-first
+			// traversal of the transitions.
+			parents := lts.Parents
+			lts.Parents = nil
+			if want := getShortestPaths(lts); !reflect.DeepEqual(parents, want) {
+				t.Errorf("got parents %v, want %v", parents, want)
+			}
+		})
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	outputPaths = true
+	defer func() {
+		outputPaths = false
+	}()
+	lts, err := generateLts([]byte(`a(x).x'<a>.0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretty := string(generatePrettyLts(lts))
+	wantPaths := "s0 :\ns1 : 1 1\ns2 : 1 2*\ns3 : 1 1, 1'1"
+	if !strings.HasSuffix(pretty, "\n\n"+wantPaths) {
+		t.Errorf("got pretty-printed LTS\n%s\nwant paths\n%s", pretty, wantPaths)
+	}
+
+	var output jsonLts
+	if err := json.Unmarshal(generateJsonLts(lts), &output); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, state := range output.States {
+		if state.Path == nil {
+			t.Fatalf("no path to s%d", state.Id)
+		}
+		paths = append(paths, strings.Join(*state.Path, ", "))
+	}
+	if want := []string{"", "1 1", "1 2*", "1 1, 1'1"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got JSON paths %q, want %q", paths, want)
+	}
+	if len(output.Transitions) != len(lts.Transitions) {
+		t.Errorf("got %d JSON transitions, want %d", len(output.Transitions), len(lts.Transitions))
+	}
+
+	dot := string(GenerateGraphVizFile(lts, true))
+	for _, tooltip := range []string{`tooltip="ε"`, `tooltip="1 1, 1' 1"`} {
+		if !strings.Contains(dot, tooltip) {
+			t.Errorf("got DOT file\n%s\nwant %s", dot, tooltip)
+		}
+	}
+}
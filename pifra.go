@@ -1,7 +1,6 @@
 package pifra
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -16,6 +15,29 @@ type Flags struct {
 	RegisterSize int
 	MaxStates    int
 	DisableGC    bool
+	Evict        bool
+	PartialOrder bool
+	Closed       bool
+	Observe      bool
+	Symmetry     bool
+	Minimise     string
+	Weak         bool
+	TracesEqual  bool
+	Deadlocks    bool
+	Divergences  bool
+	Find         string
+	Secret       string
+	Environment  string
+	Late         bool
+	Symbolic     bool
+	Fairness     string
+	Walks        int
+	WalkLength   int
+	Seed         int64
+	Weights      string
+	Replay       string
+	Explain      bool
+	Chart        string
 
 	InputFile  string
 	OutputFile string
@@ -26,6 +48,8 @@ type Flags struct {
 
 	Pretty     bool
 	Gob        bool
+	JSON       bool
+	Paths      bool
 	Statistics bool
 
 	Quiet bool
@@ -35,23 +59,18 @@ func initFlags(flags Flags) {
 	maxStatesExplored = flags.MaxStates
 	registerSize = flags.RegisterSize
 	disableGarbageCollection = flags.DisableGC
-}
-
-// InteractiveMode allows the user to inspect interactively the LTS in a prompt.
-func InteractiveMode(flags Flags) {
-	initFlags(flags)
-	for {
-		fmt.Print("> ")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		lts, err := generateLts([]byte(input))
-		if err != nil {
-			fmt.Printf("error: %s\n", err)
-		} else {
-			output := generatePrettyLts(lts)
-			fmt.Println(string(output))
-		}
-	}
+	evictRegisters = flags.Evict
+	partialOrderReduction = flags.PartialOrder
+	// Partial-order reduction explores the closed system.
+	closedSystem = flags.Closed || flags.PartialOrder
+	observeMarked = flags.Observe
+	symmetryReduction = flags.Symmetry
+	findName = flags.Find
+	secretBinder = flags.Secret
+	environment = flags.Environment
+	lateInputs = flags.Late
+	symbolicNames = flags.Symbolic
+	explainTransitions = flags.Explain
 }
 
 // OutputMode generates an LTS from the pi-calculus program file and either writes
@@ -59,6 +78,7 @@ func InteractiveMode(flags Flags) {
 func OutputMode(flags Flags) error {
 	initFlags(flags)
 	gvLayout = flags.GVLayout
+	outputPaths = flags.Paths
 
 	inputTimeStart := time.Now()
 	input, err := ioutil.ReadFile(flags.InputFile)
@@ -74,6 +94,40 @@ func OutputMode(flags Flags) error {
 	}
 	programElapsed := time.Since(programTimeStart)
 
+	// Analyse the LTS before minimisation, which merges the states.
+	var reports [][]byte
+	if flags.Deadlocks {
+		reports = append(reports, generateDeadlockReport(lts))
+	}
+	if flags.Divergences {
+		reports = append(reports, generateDivergenceReport(lts))
+	}
+	if flags.Find != "" {
+		rootReg := lts.States[0].Registers
+		if rootReg.GetLabel(flags.Find) == -1 {
+			return fmt.Errorf("%s is not a free name of the model", flags.Find)
+		}
+		reports = append(reports, generateFindReport(lts, flags.Find))
+	}
+	if flags.Secret != "" {
+		reports = append(reports, generateSecretReport(lts))
+	}
+
+	if flags.Divergences {
+		lts.Divergent = getDivergentStates(getDivergences(lts))
+	}
+
+	var minimiseElapsed time.Duration
+	statesExplored := lts.StatesExplored
+	statesUnique := len(lts.States)
+	if flags.Minimise != "" {
+		minimiseTimeStart := time.Now()
+		lts, err = minimiseLts(lts, flags.Minimise)
+		if err != nil {
+			return err
+		}
+		minimiseElapsed = time.Since(minimiseTimeStart)
+	}
 	var outputTime time.Duration
 
 	if !flags.Quiet {
@@ -91,6 +145,8 @@ func OutputMode(flags Flags) error {
 				output = generatePrettyLts(lts)
 			} else if flags.Gob {
 				output = generateGobFile(lts)
+			} else if flags.JSON {
+				output = generateJsonLts(lts)
 			} else if flags.GVTex {
 				output = generateGraphVizTexFile(lts, flags.GVOutputStates)
 			} else {
@@ -104,20 +160,437 @@ func OutputMode(flags Flags) error {
 		}
 	}
 
+	// Print the reports after the LTS, separated by new lines.
+	printed := !flags.Quiet && flags.OutputFile == ""
+	for _, report := range reports {
+		if printed {
+			fmt.Println()
+		}
+		fmt.Println(string(report))
+		printed = true
+	}
+
 	if flags.Statistics {
-		if !flags.Quiet && flags.OutputFile == "" {
-			// Print new line if LTS is printed to standard output.
+		if printed {
 			fmt.Println()
 		}
 		ioElapsed := inputTime + outputTime
-		fmt.Printf("states explored      %d\n", lts.StatesExplored)
+		fmt.Printf("states explored      %d\n", statesExplored)
 		fmt.Printf("states generated     %d\n", lts.StatesGenerated)
-		fmt.Printf("states unique        %d\n", len(lts.States))
+		fmt.Printf("states unique        %d\n", statesUnique)
+		if flags.Minimise != "" {
+			fmt.Printf("states minimised     %d\n", len(lts.States))
+		}
 		fmt.Printf("transitions          %d\n", len(lts.Transitions))
+		fmt.Printf("trans cache hits     %d\n", lts.TransCacheHits)
+		fmt.Printf("trans cache misses   %d\n", lts.TransCacheMisses)
+		if flags.PartialOrder {
+			fmt.Printf("states pruned (POR)  %d\n", lts.StatesPruned)
+		}
+		if flags.Symmetry {
+			fmt.Printf("orbits collapsed     %d\n", lts.OrbitsCollapsed)
+			fmt.Printf("states collapsed     %d\n", lts.StatesCollapsed)
+		}
+		if flags.Evict {
+			fmt.Printf("names evicted        %d\n", lts.NamesEvicted)
+		}
 		fmt.Printf("time I/O             %s\n", ioElapsed)
 		fmt.Printf("time LTS generation  %s\n", programElapsed)
+		if flags.Minimise != "" {
+			fmt.Printf("time minimisation    %s\n", minimiseElapsed)
+		}
+	}
+
+	return nil
+}
+
+// EquivMode generates the LTSs of two pi-calculus program files and decides
+// whether their roots are bisimilar, printing a distinguishing trace if not.
+func EquivMode(flags Flags, specFile string, implFile string) (bool, error) {
+	initFlags(flags)
+
+	var ltss [2]Lts
+	for i, file := range []string{specFile, implFile} {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		ltss[i], err = generateLts(input)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", file, err)
+		}
+	}
+
+	equiv := "strongly"
+	if flags.Weak {
+		equiv = "weakly"
+	}
+	var result bisimResult
+	if flags.Symbolic {
+		result = checkSymbolicBisimilarity(ltss[0], ltss[1])
+	} else {
+		result = checkBisimilarity(ltss[0], ltss[1], flags.Weak)
+	}
+	if result.Bisimilar {
+		fmt.Printf("%s and %s are %s bisimilar\n", specFile, implFile, equiv)
+		if result.Bounded {
+			fmt.Printf("up to the %d states explored\n", flags.MaxStates)
+		}
+	} else {
+		fmt.Printf("%s and %s are not %s bisimilar\n", specFile, implFile, equiv)
+		fmt.Println(prettyPrintBisimTrace(result.Trace, [2]string{specFile, implFile}))
+		if !flags.Symbolic {
+			fmt.Printf("%s satisfies and %s does not satisfy\n", specFile, implFile)
+			fmt.Println(prettyPrintHml(result.Formula))
+		}
+	}
+
+	if flags.Statistics {
+		fmt.Println()
+		fmt.Printf("states spec          %d\n", len(ltss[0].States))
+		fmt.Printf("states impl          %d\n", len(ltss[1].States))
+		fmt.Printf("pairs explored       %d\n", result.Pairs)
+	}
+	return result.Bisimilar, nil
+}
+
+// TracesMode decides whether the traces of an implementation program file are
+// included in those of a specification, and with TracesEqual the converse.
+func TracesMode(flags Flags, implFile string, specFile string) (bool, error) {
+	initFlags(flags)
+
+	ltss := make(map[string]Lts)
+	for _, file := range []string{implFile, specFile} {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		ltss[file], err = generateLts(input)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", file, err)
+		}
+	}
+
+	checks := [][2]string{{implFile, specFile}}
+	if flags.TracesEqual {
+		checks = append(checks, [2]string{specFile, implFile})
+	}
+	included := true
+	for i, check := range checks {
+		if i > 0 {
+			fmt.Println()
+		}
+		result := checkTraceInclusion(ltss[check[0]], ltss[check[1]], flags.Weak)
+		if result.Included {
+			fmt.Printf("traces of %s are included in %s\n", check[0], check[1])
+			if result.Bounded {
+				fmt.Printf("up to the %d states explored\n", flags.MaxStates)
+			}
+		} else {
+			included = false
+			fmt.Printf("%s has a trace not in %s\n", check[0], check[1])
+			fmt.Println(prettyPrintTrace(result.Trace, check[0]))
+		}
+		if flags.Statistics {
+			fmt.Printf("subsets explored     %d\n", result.Macros)
+		}
+	}
+	return included, nil
+}
+
+// CheckMode generates the LTS of a pi-calculus program file and decides
+// whether its root satisfies a modal mu-calculus formula, which is unknown if
+// this depends on the transitions of states which were not explored.
+func CheckMode(flags Flags, file string, input string) (bool, error) {
+	initFlags(flags)
+
+	formula, err := parseMuFormula(input)
+	if err != nil {
+		return false, err
+	}
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	lts, err := generateLts(program)
+	if err != nil {
+		return false, err
+	}
+
+	result, iterations := checkMuFormula(lts, formula)
+	switch result {
+	case muHolds:
+		fmt.Printf("%s satisfies %s\n", file, input)
+	case muFails:
+		fmt.Printf("%s does not satisfy %s\n", file, input)
+	default:
+		var unexplored int
+		for id := range lts.States {
+			if !lts.isExplored(id) {
+				unexplored++
+			}
+		}
+		fmt.Printf("%s may or may not satisfy %s\n", file, input)
+		fmt.Printf("states unexplored    %d\n", unexplored)
+	}
+
+	if flags.Statistics {
+		fmt.Println()
+		fmt.Printf("states               %d\n", len(lts.States))
+		fmt.Printf("transitions          %d\n", len(lts.Transitions))
+		fmt.Printf("fixpoint iterations  %d\n", iterations)
+	}
+	return result == muHolds, nil
+}
+
+// LtlMode generates the LTS of a pi-calculus program file and decides whether
+// every path from its root satisfies an LTL formula, under the fairness
+// assumption of the flags, printing a lasso-shaped counterexample if not.
+func LtlMode(flags Flags, file string, input string) (bool, error) {
+	initFlags(flags)
+
+	formula, err := parseLtlFormula(input)
+	if err != nil {
+		return false, err
+	}
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	lts, err := generateLts(program)
+	if err != nil {
+		return false, err
+	}
+
+	fairness := ""
+	if flags.Fairness != "" {
+		fairness = " under " + flags.Fairness + " fairness"
+	}
+	result := checkLtlFormula(lts, formula, flags.Fairness)
+	if result.Satisfied {
+		fmt.Printf("%s satisfies %s%s\n", file, input, fairness)
+		if result.Bounded {
+			fmt.Printf("up to the %d states explored\n", flags.MaxStates)
+		}
+	} else {
+		fmt.Printf("%s does not satisfy %s%s\n", file, input, fairness)
+		fmt.Println(prettyPrintLasso(result.Prefix, result.Cycle))
 	}
 
+	if flags.Statistics {
+		fmt.Println()
+		fmt.Printf("states               %d\n", len(lts.States))
+		fmt.Printf("automaton states     %d\n", result.Nodes)
+		fmt.Printf("product states       %d\n", result.Products)
+	}
+	return result.Satisfied, nil
+}
+
+// LateMode generates the LTS of a pi-calculus program file with late input
+// semantics, and checks that it corresponds to the early semantics.
+func LateMode(flags Flags, file string) (bool, error) {
+	flags.Late = true
+	initFlags(flags)
+
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	lts, err := generateLts(program)
+	if err != nil {
+		return false, err
+	}
+
+	checked, mismatches := checkLateCorrespondence(lts)
+	if mismatches == nil {
+		fmt.Printf("late and early semantics of %s correspond\n", file)
+		if lts.StatesExplored < len(lts.States) {
+			fmt.Printf("up to the %d states explored\n", lts.StatesExplored)
+		}
+	} else {
+		fmt.Printf("late and early semantics of %s do not correspond\n", file)
+		for _, mismatch := range mismatches {
+			fmt.Println()
+			if mismatch.Instance == "" {
+				fmt.Printf("s%d\n", mismatch.State)
+			} else {
+				fmt.Printf("s%d with %s\n", mismatch.State, mismatch.Instance)
+			}
+			for _, trn := range mismatch.Early {
+				fmt.Printf("early only    %s\n", trn)
+			}
+			for _, trn := range mismatch.Late {
+				fmt.Printf("late only     %s\n", trn)
+			}
+		}
+	}
+
+	if flags.Statistics {
+		lateInputs = false
+		earlyLts, err := generateLts(program)
+		if err != nil {
+			return false, err
+		}
+		fmt.Println()
+		fmt.Printf("late states          %d\n", len(lts.States))
+		fmt.Printf("late transitions     %d\n", len(lts.Transitions))
+		fmt.Printf("early states         %d\n", len(earlyLts.States))
+		fmt.Printf("early transitions    %d\n", len(earlyLts.Transitions))
+		fmt.Printf("instances checked    %d\n", checked)
+	}
+	return mismatches == nil, nil
+}
+
+// SimulateMode takes random walks from the root of a pi-calculus program
+// file, printing the trace of each walk, the number of distinct states
+// reached, and the depth at which each marked name was first hit.
+func SimulateMode(flags Flags, file string) error {
+	initFlags(flags)
+	weights, err := parseWeights(flags.Weights)
+	if err != nil {
+		return err
+	}
+
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	proc, err := InitProgram(program)
+	if err != nil {
+		return err
+	}
+	root, namesMap := newRootConf(proc)
+	if evictRegisters && len(root.Registers.Registers) > registerSize {
+		return fmt.Errorf("the %d free names of the model exceed the %d registers",
+			len(root.Registers.Registers), registerSize)
+	}
+
+	sim := simulate(root, flags.Walks, flags.WalkLength, flags.Seed, weights)
+	fmt.Println(string(generateSimulationReport(sim, namesMap, !flags.Quiet)))
+	return nil
+}
+
+// AcceptsMode decides whether a trace file is a trace of a pi-calculus
+// program file, printing the longest prefix accepted if not.
+func AcceptsMode(flags Flags, file string, traceFile string) (bool, error) {
+	initFlags(flags)
+	input, err := ioutil.ReadFile(traceFile)
+	if err != nil {
+		return false, err
+	}
+	labels, named, err := parseTrace(string(input))
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", traceFile, err)
+	}
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	proc, err := InitProgram(program)
+	if err != nil {
+		return false, err
+	}
+	root, namesMap := newRootConf(proc)
+	if evictRegisters && len(root.Registers.Registers) > registerSize {
+		return false, fmt.Errorf("the %d free names of the model exceed the %d registers",
+			len(root.Registers.Registers), registerSize)
+	}
+
+	result := checkAcceptance(root, namesMap, labels, named)
+	accepted := result.Accepted == len(labels)
+	if accepted {
+		fmt.Printf("%s is accepted by %s\n", traceFile, file)
+	} else {
+		fmt.Printf("%s is not accepted by %s\n", traceFile, file)
+		fmt.Printf("longest accepted prefix of %d of %d labels\n", result.Accepted, len(labels))
+	}
+	if report := generateAcceptReport(result, labels, named); report != "" {
+		fmt.Println(report)
+	}
+
+	if flags.Statistics {
+		fmt.Println()
+		fmt.Printf("states reached       %d\n", len(result.States))
+		fmt.Printf("final states         %d\n", len(result.Items))
+	}
+	return accepted, nil
+}
+
+// PathMode generates the LTS of a pi-calculus program file and prints a
+// shortest trace from its root to a state, and the state.
+func PathMode(flags Flags, file string, state string) error {
+	initFlags(flags)
+	id, err := parseStateId(state)
+	if err != nil {
+		return err
+	}
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	lts, err := generateLts(program)
+	if err != nil {
+		return err
+	}
+	if _, ok := lts.States[id]; !ok {
+		return fmt.Errorf("s%d is not a state of the LTS of the %d states explored", id, lts.StatesExplored)
+	}
+
+	if id != 0 {
+		fmt.Println(prettyPrintPath(getPath(getShortestPaths(lts), id)))
+	}
+	conf := lts.States[id]
+	fmt.Printf("s%d = %s |- %s\n", id, PrettyPrintRegister(conf.Registers), PrettyPrintAst(conf.Process))
+	return nil
+}
+
+// MscMode prints a message sequence chart of a trace file, or of a shortest
+// trace to a state, of a pi-calculus program file.
+func MscMode(flags Flags, file string, trace string) error {
+	flags.Explain = true
+	initFlags(flags)
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var steps []chartStep
+	if id, err := parseStateId(trace); err == nil {
+		lts, err := generateLts(program)
+		if err != nil {
+			return err
+		}
+		if _, ok := lts.States[id]; !ok {
+			return fmt.Errorf("s%d is not a state of the LTS of the %d states explored", id, lts.StatesExplored)
+		}
+		steps = getPathChartSteps(lts, getPath(getShortestPaths(lts), id))
+	} else {
+		input, err := ioutil.ReadFile(trace)
+		if err != nil {
+			return err
+		}
+		labels, named, err := parseTrace(string(input))
+		if err != nil {
+			return fmt.Errorf("%s: %s", trace, err)
+		}
+		proc, err := InitProgram(program)
+		if err != nil {
+			return err
+		}
+		root, namesMap := newRootConf(proc)
+		if evictRegisters && len(root.Registers.Registers) > registerSize {
+			return fmt.Errorf("the %d free names of the model exceed the %d registers",
+				len(root.Registers.Registers), registerSize)
+		}
+		result := checkAcceptance(root, namesMap, labels, named)
+		if result.Accepted < len(labels) {
+			return fmt.Errorf("%s is not accepted by %s: line %d is not enabled",
+				trace, file, labels[result.Accepted].Line)
+		}
+		steps = getTraceChartSteps(result, labels)
+	}
+
+	fmt.Println(generateChart(getChartMessages(steps, program), flags.Chart))
 	return nil
 }
 
@@ -132,7 +605,16 @@ func generateLts(input []byte) (Lts, error) {
 	if err != nil {
 		return Lts{}, err
 	}
+	if secretBinder != "" {
+		if err := markSecretBinder(proc); err != nil {
+			return Lts{}, err
+		}
+	}
 	root, namesMap := newRootConf(proc)
+	if evictRegisters && len(root.Registers.Registers) > registerSize {
+		return Lts{}, fmt.Errorf("the %d free names of the model exceed the %d registers",
+			len(root.Registers.Registers), registerSize)
+	}
 	lts := explore(root)
 	lts.FreeNamesMap = namesMap
 	return lts, nil
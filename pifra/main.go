@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/yungene/pifra"
@@ -40,21 +41,23 @@ var rootCmd = &cobra.Command{
 	Short: "LTS generator for the pi-calculus represented by FRA.",
 	Long: `pifra generates labelled transition systems (LTS) of
 pi-calculus models represented by fresh-register automata.`,
+	// Allow the input file as an argument alongside subcommands.
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		if flags.RegisterSize < 0 {
-			fmt.Println("error: register size must be positive. 0 defaults to unlimited.")
-			os.Exit(1)
-		}
-		if flags.RegisterSize == 0 {
-			flags.RegisterSize = 1073741824
-		}
-
-		if flags.MaxStates < 0 {
-			fmt.Println("error: maximum states explored must be positive")
-			os.Exit(1)
-		}
+		checkFlags(pifra.CommandLts)
 		if flags.InteractiveMode {
-			pifra.InteractiveMode(flags)
+			if len(args) > 1 {
+				fmt.Println("error: more than one argument encountered")
+				fmt.Printf(cmd.UsageString())
+				os.Exit(1)
+			}
+			if len(args) == 1 {
+				flags.InputFile = args[0]
+			}
+			if err := pifra.InteractiveMode(flags); err != nil {
+				fmt.Println("error:", err)
+				os.Exit(1)
+			}
 		} else {
 			if len(args) < 1 {
 				fmt.Println("error: input file required for LTS generation")
@@ -75,7 +78,271 @@ pi-calculus models represented by fresh-register automata.`,
 	},
 }
 
+var equivCmd = &cobra.Command{
+	Use:                   "equiv [OPTION...] SPEC IMPL",
+	DisableFlagsInUseLine: true,
+	Short:                 "Decide whether two pi-calculus models are bisimilar.",
+	Long: `equiv generates the LTSs of a specification and an implementation
+model and decides whether they are strongly or weakly bisimilar, printing a
+distinguishing trace from both roots if they are not.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandEquiv)
+		if len(args) != 2 {
+			fmt.Println("error: specification and implementation files required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		bisimilar, err := pifra.EquivMode(flags, args[0], args[1])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !bisimilar {
+			os.Exit(1)
+		}
+	},
+}
+
+var tracesCmd = &cobra.Command{
+	Use:                   "traces-included [OPTION...] IMPL SPEC",
+	DisableFlagsInUseLine: true,
+	Short:                 "Decide whether the traces of a model are included in another.",
+	Long: `traces-included generates the LTSs of an implementation and a
+specification model and decides whether every trace of the implementation is
+a trace of the specification, printing a shortest offending trace if not.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandTraces)
+		if len(args) != 2 {
+			fmt.Println("error: implementation and specification files required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		included, err := pifra.TracesMode(flags, args[0], args[1])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !included {
+			os.Exit(1)
+		}
+	},
+}
+
+var checkCmd = &cobra.Command{
+	Use:                   "check [OPTION...] FILE FORMULA",
+	DisableFlagsInUseLine: true,
+	Short:                 "Decide whether a pi-calculus model satisfies a mu-calculus formula.",
+	Long: `check generates the LTS of a model and decides whether its root
+satisfies a modal mu-calculus formula, e.g.,
+nu X.([1'_^]mu Y.(<1 2>tt | <->tt & [-]Y) & [-]X).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandCheck)
+		if len(args) != 2 {
+			fmt.Println("error: input file and formula required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		satisfied, err := pifra.CheckMode(flags, args[0], args[1])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !satisfied {
+			os.Exit(1)
+		}
+	},
+}
+
+var ltlCmd = &cobra.Command{
+	Use:                   "ltl [OPTION...] FILE FORMULA",
+	DisableFlagsInUseLine: true,
+	Short:                 "Decide whether the paths of a pi-calculus model satisfy an LTL formula.",
+	Long: `ltl generates the LTS of a model and decides whether every path from its
+root satisfies a linear temporal logic formula over the labels of the LTS,
+e.g., G({1'_^} -> F{2 _?}), printing a lasso-shaped counterexample if not.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandLtl)
+		if len(args) != 2 {
+			fmt.Println("error: input file and formula required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		satisfied, err := pifra.LtlMode(flags, args[0], args[1])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !satisfied {
+			os.Exit(1)
+		}
+	},
+}
+
+var lateCmd = &cobra.Command{
+	Use:                   "late [OPTION...] FILE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Check the late input semantics of a model against the early semantics.",
+	Long: `late generates the LTS of a model with late input semantics, and checks
+that the transitions of every instance of its states correspond to the early
+semantics, printing the transitions of each instance which do not.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandLate)
+		if len(args) != 1 {
+			fmt.Println("error: input file required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		correspond, err := pifra.LateMode(flags, args[0])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !correspond {
+			os.Exit(1)
+		}
+	},
+}
+
+var simulateCmd = &cobra.Command{
+	Use:                   "simulate [OPTION...] FILE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Take random walks through the transitions of a pi-calculus model.",
+	Long: `simulate takes random walks from the root of a model without generating
+its LTS, choosing transitions by the weights of their label types.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandSimulate)
+		if len(args) != 1 {
+			fmt.Println("error: input file required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		if err := pifra.SimulateMode(flags, args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var stepCmd = &cobra.Command{
+	Use:                   "step [OPTION...] FILE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Simulate a pi-calculus model interactively, one transition at a time.",
+	Long: `step shows the current state of a model with its transitions numbered,
+and fires the transitions chosen in a prompt. Enter h for the commands.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandStep)
+		if len(args) != 1 {
+			fmt.Println("error: input file required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		if err := pifra.StepMode(flags, args[0]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var acceptsCmd = &cobra.Command{
+	Use:                   "accepts [OPTION...] FILE TRACE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Decide whether a trace is a trace of a pi-calculus model.",
+	Long: `accepts decides whether a trace file, with a label per line either
+pretty-printed (e.g., 1 2*) or with names (e.g., a b), is a trace of a model.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandAccepts)
+		if len(args) != 2 {
+			fmt.Println("error: input file and trace file required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		accepted, err := pifra.AcceptsMode(flags, args[0], args[1])
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		if !accepted {
+			os.Exit(1)
+		}
+	},
+}
+
+var pathCmd = &cobra.Command{
+	Use:                   "path [OPTION...] FILE STATE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Print a shortest trace from the root of a pi-calculus model to a state.",
+	Long: `path generates the LTS of a model and prints a shortest trace from its
+root to a state of the LTS, e.g., s137, followed by the state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandPath)
+		if len(args) != 2 {
+			fmt.Println("error: input file and state required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		if err := pifra.PathMode(flags, args[0], args[1]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var mscCmd = &cobra.Command{
+	Use:                   "msc [OPTION...] FILE TRACE",
+	DisableFlagsInUseLine: true,
+	Short:                 "Print a message sequence chart of a trace of a pi-calculus model.",
+	Long: `msc prints a message sequence chart of a trace file, or of a shortest trace
+to a state of the LTS (e.g., s137), between the environment and the
+instances of the process definitions, e.g., Server#1.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkFlags(pifra.CommandMsc)
+		if len(args) != 2 {
+			fmt.Println("error: input file and trace required")
+			fmt.Printf(cmd.UsageString())
+			os.Exit(1)
+		}
+		if err := pifra.MscMode(flags, args[0], args[1]); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// checkFlags validates the global flags for a command and sets their
+// defaults.
+func checkFlags(command string) {
+	if flags.RegisterSize < 0 {
+		fmt.Println("error: register size must be positive. 0 defaults to unlimited.")
+		os.Exit(1)
+	}
+	if flags.MaxStates < 0 {
+		fmt.Println("error: maximum states explored must be positive")
+		os.Exit(1)
+	}
+	if err := pifra.ValidateFlags(flags, command); err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	if flags.RegisterSize == 0 {
+		flags.RegisterSize = 1073741824
+	}
+}
+
+// normaliseArgs rewrites the single-dash -len of simulate to --len, which
+// would otherwise be parsed as the shorthand -l with the value en.
+func normaliseArgs(args []string) []string {
+	normalised := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "-len" || strings.HasPrefix(arg, "-len=") {
+			arg = "-" + arg
+		}
+		normalised[i] = arg
+	}
+	return normalised
+}
+
 func execute() {
+	rootCmd.SetArgs(normaliseArgs(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -91,21 +358,71 @@ func init() {
 
 	rootCmd.PersistentFlags().IntVarP(&flags.MaxStates, "max-states", "n", 20, "maximum number of states explored")
 	rootCmd.PersistentFlags().IntVarP(&flags.RegisterSize, "max-registers", "r", 0, "maximum number of registers (default is unlimited)")
+	rootCmd.PersistentFlags().BoolVar(&flags.Evict, "evict", false, "with --max-registers, evict names not free in the process before leaving states with more registers unexplored")
 	rootCmd.PersistentFlags().BoolVarP(&flags.DisableGC, "disable-gc", "d", false, "disable garbage collection")
+	rootCmd.PersistentFlags().BoolVar(&flags.Closed, "closed", false, "explore only τ-transitions, i.e., communications within the model")
+	rootCmd.PersistentFlags().BoolVar(&flags.Observe, "observe-marked", false, "with --closed or --por, also explore outputs on marked names")
+	rootCmd.PersistentFlags().BoolVar(&flags.PartialOrder, "por", false, "explore only τ-transitions with partial-order reduction")
+	rootCmd.PersistentFlags().BoolVar(&flags.Symmetry, "symmetry", false, "identify states equal up to a permutation of registers")
+	rootCmd.PersistentFlags().StringVar(&flags.Environment, "environment", "", "restrict the known names sent by the environment to those it knows: outputs, public or dolev-yao")
+	rootCmd.PersistentFlags().BoolVar(&flags.Late, "late", false, "explore inputs with late semantics, i.e., a placeholder for the name received")
+	rootCmd.PersistentFlags().BoolVar(&flags.Symbolic, "symbolic", false, "explore with symbolic semantics, i.e., late inputs and constraints on transitions instead of instantiations")
+	rootCmd.PersistentFlags().StringVar(&flags.Minimise, "minimise", "", "minimise the LTS by strong, weak or branching bisimilarity")
 
-	rootCmd.PersistentFlags().BoolVarP(&flags.InteractiveMode, "interactive", "i", false, "inspect interactively the LTS in a prompt")
+	rootCmd.PersistentFlags().BoolVarP(&flags.InteractiveMode, "interactive", "i", false, "generate LTSs of processes in a prompt, with the definitions of the session and of FILE, if given")
 	rootCmd.PersistentFlags().StringVarP(&flags.OutputFile, "output", "o", "", "output the LTS to a file (default format is the Graphviz DOT language)")
 	rootCmd.PersistentFlags().BoolVarP(&flags.GVTex, "output-tex", "t", false, "output the LTS file with LaTeX labels for use with dot2tex")
 	rootCmd.PersistentFlags().BoolVarP(&flags.Pretty, "output-pretty", "p", false, "output the LTS file in a pretty-printed format")
 	rootCmd.PersistentFlags().BoolVarP(&flags.Gob, "output-gob", "g", false, "output the LTS file in a binary gob encoding")
+	rootCmd.PersistentFlags().BoolVarP(&flags.JSON, "output-json", "j", false, "output the LTS file in JSON")
+	rootCmd.PersistentFlags().BoolVar(&flags.Explain, "explain", false, "explain each transition by the rules of the semantics and the positions of the prefixes deriving it, in the pretty-printed output and as a tooltip in the Graphviz DOT file")
+	rootCmd.PersistentFlags().BoolVar(&flags.Paths, "paths", false, "output a shortest trace from the root to each state in the pretty-printed and JSON outputs, and as a tooltip in the Graphviz DOT file")
 
 	rootCmd.PersistentFlags().BoolVarP(&flags.GVOutputStates, "output-states", "s", false, "output state numbers instead of configurations for the Graphviz DOT file")
 	rootCmd.PersistentFlags().StringVarP(&flags.GVLayout, "output-layout", "l", "", "layout of the GraphViz DOT file, e.g., \"rankdir=TB; margin=0;\"")
 
 	rootCmd.PersistentFlags().BoolVarP(&flags.Quiet, "quiet", "q", false, "do not print or output the LTS")
 	rootCmd.PersistentFlags().BoolVarP(&flags.Statistics, "stats", "v", false, "print LTS generation statistics")
+	rootCmd.PersistentFlags().BoolVar(&flags.Deadlocks, "deadlocks", false, "report deadlocks and terminations with a shortest trace to each deadlock")
+	rootCmd.PersistentFlags().BoolVar(&flags.Divergences, "divergences", false, "report τ-cycles with a shortest trace to each, and highlight them in the Graphviz DOT file")
+	rootCmd.PersistentFlags().StringVar(&flags.Find, "find", "", "stop at the first transition on a marked name, e.g., _BAD, and print a shortest trace to it")
+	rootCmd.PersistentFlags().StringVar(&flags.Secret, "secret", "", "stop at the first leak of the names of a restriction, e.g., pass@GenPass, and print a shortest trace to it")
 
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "show this help message and exit")
+
+	equivCmd.Flags().SortFlags = false
+	equivCmd.Flags().BoolVarP(&flags.Weak, "weak", "w", false, "decide weak instead of strong bisimilarity")
+	rootCmd.AddCommand(equivCmd)
+
+	tracesCmd.Flags().SortFlags = false
+	tracesCmd.Flags().BoolVarP(&flags.Weak, "weak", "w", false, "exclude τ-transitions from traces")
+	tracesCmd.Flags().BoolVarP(&flags.TracesEqual, "equal", "e", false, "also decide the converse inclusion, i.e., trace equivalence")
+	rootCmd.AddCommand(tracesCmd)
+
+	rootCmd.AddCommand(checkCmd)
+
+	rootCmd.AddCommand(acceptsCmd)
+
+	ltlCmd.Flags().SortFlags = false
+	ltlCmd.Flags().StringVarP(&flags.Fairness, "fairness", "f", "", "assume weak or strong fairness on the parallel components")
+	rootCmd.AddCommand(ltlCmd)
+
+	rootCmd.AddCommand(lateCmd)
+
+	mscCmd.Flags().StringVarP(&flags.Chart, "format", "f", pifra.ChartMermaid, "format of the chart: mermaid or plantuml")
+	rootCmd.AddCommand(mscCmd)
+
+	rootCmd.AddCommand(pathCmd)
+
+	simulateCmd.Flags().SortFlags = false
+	simulateCmd.Flags().IntVarP(&flags.Walks, "walks", "k", 100, "number of random walks")
+	simulateCmd.Flags().IntVar(&flags.WalkLength, "len", 50, "maximum number of transitions of a walk")
+	simulateCmd.Flags().Int64Var(&flags.Seed, "seed", 1, "seed of the random choices")
+	simulateCmd.Flags().StringVar(&flags.Weights, "weights", "", "weights of label types, e.g., \"tau=2,fresh-input=0\" (default 1 for each of tau, input, fresh-input, output and fresh-output)")
+	rootCmd.AddCommand(simulateCmd)
+
+	stepCmd.Flags().StringVar(&flags.Replay, "replay", "", "replay a trace file written in a previous session")
+	rootCmd.AddCommand(stepCmd)
 }
 
 func main() {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulateArgs(t *testing.T) {
+	tests := map[string][]string{
+		"single_dash": {"simulate", "-k", "1000", "-len", "50", "model.pi"},
+		"double_dash": {"simulate", "-k", "1000", "--len", "50", "model.pi"},
+		"equals":      {"simulate", "-k", "1000", "-len=50", "model.pi"},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			flags.Walks, flags.WalkLength, flags.GVLayout = 0, 0, ""
+			cmd, rest, err := rootCmd.Find(normaliseArgs(args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.ParseFlags(rest); err != nil {
+				t.Fatal(err)
+			}
+			if flags.Walks != 1000 || flags.WalkLength != 50 || flags.GVLayout != "" {
+				t.Errorf("got walks %d, length %d and layout %q, want 1000, 50 and none",
+					flags.Walks, flags.WalkLength, flags.GVLayout)
+			}
+			if files := cmd.Flags().Args(); !reflect.DeepEqual(files, []string{"model.pi"}) {
+				t.Errorf("got arguments %q, want model.pi", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package pifra
+
+import (
+	"github.com/mohae/deepcopy"
+)
+
+// partialOrderReduction explores only τ-transitions, and in each state only
+// those of a single component without visible transitions, unless one leads
+// to a visited state. It preserves deadlocks.
+var partialOrderReduction bool
+
+func isTauConf(conf Configuration) bool {
+	return conf.Label.Symbol.Type == SymbolTypTau
+}
+
+// filterTauConfs returns the configurations reached by τ-transitions.
+func filterTauConfs(confs []Configuration) []Configuration {
+	var tauConfs []Configuration
+	for _, conf := range confs {
+		if isTauConf(conf) {
+			tauConfs = append(tauConfs, conf)
+		}
+	}
+	return tauConfs
+}
+
+// getNormalisedKey returns the state key of a normalised copy of the
+// configuration, leaving the configuration itself untouched.
+func getNormalisedKey(conf Configuration) string {
+	c := deepcopy.Copy(conf).(Configuration)
+	applyStructrualCongruence(c)
+	key, _ := getStateKey(c)
+	return key
+}
+
+// getComponents splits the process of a state into its top-level restricted
+// names and parallel components.
+func getComponents(elem Element) ([]Name, []Element) {
+	if elem.Type() == ElemTypRoot {
+		elem = elem.(*ElemRoot).Next
+	}
+	resNames, elem := getRes(elem, []Name{})
+	if elem.Type() != ElemTypParallel {
+		return resNames, []Element{elem}
+	}
+	return resNames, getPar(elem)
+}
+
+// buildComponents reassembles a state process from its top-level restricted
+// names and parallel components. Undos getComponents.
+func buildComponents(resNames []Name, comps []Element) Element {
+	elem := comps[len(comps)-1]
+	for i := len(comps) - 2; i >= 0; i-- {
+		elem = &ElemParallel{
+			ProcessL: comps[i],
+			ProcessR: elem,
+		}
+	}
+	for i := len(resNames) - 1; i >= 0; i-- {
+		elem = &ElemRestriction{
+			Restrict: resNames[i],
+			Next:     elem,
+		}
+	}
+	return &ElemRoot{
+		Next: elem,
+	}
+}
+
+// reducePartialOrder returns an ample subset of the τ-successors of a state,
+// and the keys of the successors which were pruned.
+func reducePartialOrder(state Configuration, confs []Configuration, visited map[string]int) ([]Configuration, []string) {
+	confs = filterClosedConfs(confs)
+
+	resNames, comps := getComponents(state.Process)
+	if len(comps) < 2 || len(confs) < 2 {
+		return confs, nil
+	}
+
+	// Find the component with the fewest transitions, all of which are internal.
+	var ample []Configuration
+	for i := range comps {
+		compConf := Configuration{
+			Process:   deepcopy.Copy(comps[i]).(Element),
+			Registers: deepcopy.Copy(state.Registers).(Registers),
+		}
+		// Place the restricted names in the register as in RES, so that
+		// distinct restricted channels have distinct labels.
+		for _, resName := range resNames {
+			compConf.Registers.UpdateMax(resName.Name)
+		}
+		tconfs := transComponent(compConf)
+		if len(tconfs) == 0 || len(filterTauConfs(tconfs)) != len(tconfs) {
+			continue
+		}
+		if ample != nil && len(tconfs) >= len(ample) {
+			continue
+		}
+
+		ample = []Configuration{}
+		for _, tconf := range tconfs {
+			procs := deepcopy.Copy(comps).([]Element)
+			procs[i] = tconf.Process
+			ample = append(ample, Configuration{
+				Process:   buildComponents(resNames, procs),
+				Registers: deepcopy.Copy(state.Registers).(Registers),
+				Label:     tconf.Label,
+			})
+		}
+	}
+	if ample == nil || len(ample) == len(confs) {
+		return confs, nil
+	}
+
+	ampleKeys := make(map[string]bool)
+	for _, conf := range ample {
+		key := getNormalisedKey(conf)
+		// Cycle proviso.
+		if _, ok := visited[key]; ok {
+			return confs, nil
+		}
+		ampleKeys[key] = true
+	}
+
+	var reduced []Configuration
+	var pruned []string
+	found := make(map[string]bool)
+	for _, conf := range confs {
+		key := getNormalisedKey(conf)
+		if ampleKeys[key] {
+			found[key] = true
+			reduced = append(reduced, conf)
+		} else {
+			pruned = append(pruned, key)
+		}
+	}
+	// Fall back to a full expansion if an ample transition is not among the
+	// transitions of the state.
+	if len(found) != len(ampleKeys) {
+		return confs, nil
+	}
+	return reduced, pruned
+}
@@ -0,0 +1,99 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartialOrderReduction(t *testing.T) {
+	tests := map[string]struct {
+		input        []byte
+		states       int
+		statesPruned int
+	}{
+		"independent_components": {
+			input: []byte(`
+$a.(a'<e>.0 | a(x).0) | $b.(b'<f>.0 | b(y).0) | $c.(c'<g>.0 | c(z).0)
+`),
+			states:       4,
+			statesPruned: 3,
+		},
+		"independent_and_visible_components": {
+			input: []byte(`
+$a.(a'<e>.0 | a(x).0) | $b.(b'<f>.0 | b(y).0) | h'<g>.0 | h(z).0
+`),
+			states:       4,
+			statesPruned: 3,
+		},
+		"dependent_components": {
+			input: []byte(`
+$a.(a'<e>.0 | a(x).x'<x>.0) | e(y).0
+`),
+			states:       3,
+			statesPruned: 0,
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		partialOrderReduction = false
+	}()
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			partialOrderReduction = false
+			lts, err := generateLts(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			partialOrderReduction = true
+			reducedLts, err := generateLts(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(reducedLts.States) != tc.states {
+				t.Errorf("states: expected %d, got %d", tc.states, len(reducedLts.States))
+			}
+			if reducedLts.StatesPruned != tc.statesPruned {
+				t.Errorf("states pruned: expected %d, got %d", tc.statesPruned, reducedLts.StatesPruned)
+			}
+			for _, trn := range reducedLts.Transitions {
+				if trn.Label.Symbol.Type != SymbolTypTau {
+					t.Errorf("unexpected visible transition %s", PrettyPrintLabel(trn.Label))
+				}
+			}
+			if !reflect.DeepEqual(getTauDeadlocks(lts), getTauDeadlocks(reducedLts)) {
+				t.Errorf("deadlocks not preserved: expected %v, got %v",
+					getTauDeadlocks(lts), getTauDeadlocks(reducedLts))
+			}
+		})
+	}
+}
+
+// getTauDeadlocks returns the keys of the states reachable from the root by
+// τ-transitions which have no outgoing τ-transitions.
+func getTauDeadlocks(lts Lts) map[string]bool {
+	tauSuccs := make(map[int][]int)
+	for _, trn := range lts.Transitions {
+		if trn.Label.Symbol.Type == SymbolTypTau {
+			tauSuccs[trn.Source] = append(tauSuccs[trn.Source], trn.Destination)
+		}
+	}
+	deadlocks := make(map[string]bool)
+	seen := map[int]bool{0: true}
+	stack := []int{0}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(tauSuccs[id]) == 0 {
+			deadlocks[getConfigurationKey(lts.States[id])] = true
+		}
+		for _, dst := range tauSuccs[id] {
+			if !seen[dst] {
+				seen[dst] = true
+				stack = append(stack, dst)
+			}
+		}
+	}
+	return deadlocks
+}
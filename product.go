@@ -0,0 +1,489 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// productState is a state of the product of an LTS and a Büchi automaton.
+type productState struct {
+	State int
+	Node  int
+}
+
+// productEdge is a transition of the product, following a transition of the
+// LTS, or idling at a deadlock if the transition is nil.
+type productEdge struct {
+	From       int
+	To         int
+	Transition *Transition
+}
+
+type ltlResult struct {
+	Satisfied bool
+	// Whether a state which was not explored was reached, in which case its
+	// paths are assumed to satisfy the formula.
+	Bounded bool
+	// The states of the Büchi automaton and the product.
+	Nodes    int
+	Products int
+	// A counterexample: a path from the root to a cycle which is repeated
+	// forever.
+	Prefix []ltlStep
+	Cycle  []ltlStep
+}
+
+// ltlStep is a transition of a counterexample, or idling at a deadlock state
+// if the transition is nil.
+type ltlStep struct {
+	State      int
+	Transition *Transition
+}
+
+// ltlChecker searches the product of an LTS with a Büchi automaton of the
+// negated formula for a strongly connected component in which an accepting
+// and fair path can cycle forever.
+type ltlChecker struct {
+	lts       Lts
+	automaton buchiAutomaton
+	fairness  string
+	info      componentInfo
+
+	states  []productState
+	stateId map[productState]int
+	edges   [][]productEdge
+	bounded bool
+}
+
+// checkLtlFormula decides whether every path of an LTS from the root
+// satisfies a formula in negation normal form under a fairness assumption.
+func checkLtlFormula(lts Lts, formula ltlFormula, fairness string) ltlResult {
+	c := &ltlChecker{
+		lts:       lts,
+		automaton: newBuchiAutomaton(toLtlNegationNormalForm(formula, true)),
+		fairness:  fairness,
+		stateId:   make(map[productState]int),
+	}
+	if fairness != "" {
+		c.info = getComponentInfo(lts)
+	}
+	c.buildProduct()
+
+	result := ltlResult{
+		Satisfied: true,
+		Bounded:   c.bounded,
+		Nodes:     len(c.automaton.Literals),
+		Products:  len(c.states),
+	}
+	all := make([]int, len(c.states))
+	for id := range all {
+		all[id] = id
+	}
+	if scc := c.findFairScc(all); scc != nil {
+		result.Satisfied = false
+		prefix, cycle := c.getLasso(scc)
+		result.Prefix = c.getSteps(prefix)
+		result.Cycle = c.getSteps(cycle)
+	}
+	return result
+}
+
+func (c *ltlChecker) addState(state productState) {
+	if _, ok := c.stateId[state]; ok {
+		return
+	}
+	c.stateId[state] = len(c.states)
+	c.states = append(c.states, state)
+	c.edges = append(c.edges, nil)
+}
+
+// buildProduct generates the product states reachable from the initial
+// states. The states of the LTS which were not explored have no transitions,
+// so no path of the product passes through them.
+func (c *ltlChecker) buildProduct() {
+	trns := getTransitionsBySource(c.lts.Transitions)
+	for _, node := range c.automaton.Initial {
+		c.addState(productState{
+			State: 0,
+			Node:  node,
+		})
+	}
+	for id := 0; id < len(c.states); id++ {
+		state := c.states[id]
+		if !c.lts.isExplored(state.State) {
+			c.bounded = true
+			continue
+		}
+		// The transitions of the LTS, or idling at a deadlock.
+		var labels []*Transition
+		for i := range trns[state.State] {
+			labels = append(labels, &trns[state.State][i])
+		}
+		if len(labels) == 0 {
+			labels = []*Transition{nil}
+		}
+		for _, trn := range labels {
+			var label *Label
+			dst := state.State
+			if trn != nil {
+				label = &trn.Label
+				dst = trn.Destination
+			}
+			matched := true
+			for _, literal := range c.automaton.Literals[state.Node] {
+				if !matchLtlLiteral(literal, label) {
+					matched = false
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+			for _, node := range c.automaton.Successors[state.Node] {
+				succ := productState{
+					State: dst,
+					Node:  node,
+				}
+				c.addState(succ)
+				c.edges[id] = append(c.edges[id], productEdge{
+					From:       id,
+					To:         c.stateId[succ],
+					Transition: trn,
+				})
+			}
+		}
+	}
+}
+
+// getSccs returns the strongly connected components of the product restricted
+// to a set of states.
+func (c *ltlChecker) getSccs(states []int) [][]int {
+	allowed := make(map[int]bool)
+	for _, id := range states {
+		allowed[id] = true
+	}
+	return getStronglyConnectedComponents(states, func(id int) []int {
+		var succs []int
+		for _, edge := range c.edges[id] {
+			if allowed[edge.To] {
+				succs = append(succs, edge.To)
+			}
+		}
+		return succs
+	})
+}
+
+// getStronglyConnectedComponents returns the strongly connected components of
+// a graph, by Tarjan's algorithm, in reverse topological order.
+func getStronglyConnectedComponents(states []int, successors func(int) []int) [][]int {
+	index := make(map[int]int)
+	lowlink := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var sccs [][]int
+
+	var connect func(id int)
+	connect = func(id int) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, succ := range successors(id) {
+			if _, ok := index[succ]; !ok {
+				connect(succ)
+				if lowlink[succ] < lowlink[id] {
+					lowlink[id] = lowlink[succ]
+				}
+			} else if onStack[succ] && index[succ] < lowlink[id] {
+				lowlink[id] = index[succ]
+			}
+		}
+		if lowlink[id] == index[id] {
+			var scc []int
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				scc = append(scc, top)
+				if top == id {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, id := range states {
+		if _, ok := index[id]; !ok {
+			connect(id)
+		}
+	}
+	return sccs
+}
+
+// getSccEdges returns the edges between states of a component.
+func (c *ltlChecker) getSccEdges(scc map[int]bool) []productEdge {
+	var edges []productEdge
+	for id := range scc {
+		for _, edge := range c.edges[id] {
+			if scc[edge.To] {
+				edges = append(edges, edge)
+			}
+		}
+	}
+	return edges
+}
+
+// getMoves returns the components which move in an edge, none when idling.
+func (c *ltlChecker) getMoves(edge productEdge) map[string]bool {
+	if edge.Transition == nil {
+		return nil
+	}
+	return c.info.Moves[*edge.Transition]
+}
+
+// findFairScc returns the states of a strongly connected component in which a
+// fair path visits every acceptance set, or nil if there is none.
+func (c *ltlChecker) findFairScc(states []int) []int {
+	for _, scc := range c.getSccs(states) {
+		members := make(map[int]bool)
+		for _, id := range scc {
+			members[id] = true
+		}
+		edges := c.getSccEdges(members)
+		if len(edges) == 0 {
+			continue
+		}
+		accepting := true
+		for _, set := range c.automaton.Accepting {
+			visited := false
+			for _, id := range scc {
+				if set[c.states[id].Node] {
+					visited = true
+					break
+				}
+			}
+			accepting = accepting && visited
+		}
+		if !accepting {
+			continue
+		}
+		if c.fairness == "" {
+			return scc
+		}
+
+		moved := make(map[string]bool)
+		for _, edge := range edges {
+			for key := range c.getMoves(edge) {
+				moved[key] = true
+			}
+		}
+		unfair := make(map[string]bool)
+		for _, id := range scc {
+			for key := range c.info.Enabled[c.states[id].State] {
+				if !moved[key] {
+					unfair[key] = true
+				}
+			}
+		}
+		if c.fairness == FairnessWeak {
+			for _, id := range scc {
+				for key := range unfair {
+					if !c.info.Enabled[c.states[id].State][key] {
+						delete(unfair, key)
+					}
+				}
+			}
+			if len(unfair) == 0 {
+				return scc
+			}
+			continue
+		}
+
+		if len(unfair) == 0 {
+			return scc
+		}
+		var remaining []int
+		for _, id := range scc {
+			enabled := false
+			for key := range unfair {
+				enabled = enabled || c.info.Enabled[c.states[id].State][key]
+			}
+			if !enabled {
+				remaining = append(remaining, id)
+			}
+		}
+		if fair := c.findFairScc(remaining); fair != nil {
+			return fair
+		}
+	}
+	return nil
+}
+
+// getLasso returns a shortest path from an initial state to a component, and
+// a cycle in the component from the state reached, which visits every
+// acceptance set, and moves or disables each component enabled in it.
+func (c *ltlChecker) getLasso(scc []int) ([]productEdge, []productEdge) {
+	members := make(map[int]bool)
+	for _, id := range scc {
+		members[id] = true
+	}
+	var initial []int
+	for _, node := range c.automaton.Initial {
+		initial = append(initial, c.stateId[productState{State: 0, Node: node}])
+	}
+	prefix := c.getProductPath(initial, nil, func(id int) bool {
+		return members[id]
+	}, nil, false)
+	entry := initial[0]
+	if len(prefix) != 0 {
+		entry = prefix[len(prefix)-1].To
+	} else {
+		for _, id := range initial {
+			if members[id] {
+				entry = id
+				break
+			}
+		}
+	}
+
+	type goal struct {
+		state func(int) bool
+		edge  func(productEdge) bool
+	}
+	var goals []goal
+	for _, set := range c.automaton.Accepting {
+		set := set
+		goals = append(goals, goal{
+			state: func(id int) bool {
+				return set[c.states[id].Node]
+			},
+		})
+	}
+	if c.fairness != "" {
+		edges := c.getSccEdges(members)
+		enabled := make(map[string]bool)
+		for _, id := range scc {
+			for key := range c.info.Enabled[c.states[id].State] {
+				enabled[key] = true
+			}
+		}
+		var keys []string
+		for key := range enabled {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			key := key
+			moved := false
+			for _, edge := range edges {
+				moved = moved || c.getMoves(edge)[key]
+			}
+			if moved {
+				goals = append(goals, goal{
+					edge: func(edge productEdge) bool {
+						return c.getMoves(edge)[key]
+					},
+				})
+			} else {
+				goals = append(goals, goal{
+					state: func(id int) bool {
+						return !c.info.Enabled[c.states[id].State][key]
+					},
+				})
+			}
+		}
+	}
+
+	var cycle []productEdge
+	current := entry
+	for _, g := range goals {
+		path := c.getProductPath([]int{current}, members, g.state, g.edge, false)
+		cycle = append(cycle, path...)
+		if len(path) != 0 {
+			current = path[len(path)-1].To
+		}
+	}
+	back := c.getProductPath([]int{current}, members, func(id int) bool {
+		return id == entry
+	}, nil, len(cycle) == 0)
+	return prefix, append(cycle, back...)
+}
+
+// getProductPath returns a shortest path from one of the sources, within a
+// set of states if not nil, to a state satisfying the state goal or through
+// an edge satisfying the edge goal. With nonEmpty, the path has an edge even
+// if a source satisfies the goal.
+func (c *ltlChecker) getProductPath(sources []int, within map[int]bool, state func(int) bool,
+	edge func(productEdge) bool, nonEmpty bool) []productEdge {
+	parents := make(map[int]productEdge)
+	visited := make(map[int]bool)
+	var queue []int
+	for _, id := range sources {
+		if !nonEmpty && state != nil && state(id) {
+			return nil
+		}
+		visited[id] = true
+		queue = append(queue, id)
+	}
+	getPath := func(id int) []productEdge {
+		var path []productEdge
+		for {
+			e, ok := parents[id]
+			if !ok {
+				return path
+			}
+			path = append([]productEdge{e}, path...)
+			id = e.From
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, e := range c.edges[id] {
+			if within != nil && !within[e.To] {
+				continue
+			}
+			if (edge != nil && edge(e)) || (state != nil && state(e.To)) {
+				return append(getPath(id), e)
+			}
+			if !visited[e.To] {
+				visited[e.To] = true
+				parents[e.To] = e
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ltlChecker) getSteps(edges []productEdge) []ltlStep {
+	var steps []ltlStep
+	for _, edge := range edges {
+		steps = append(steps, ltlStep{
+			State:      c.states[edge.From].State,
+			Transition: edge.Transition,
+		})
+	}
+	return steps
+}
+
+// prettyPrintLasso returns a counterexample with a line per transition, in the
+// format of the pretty-printed LTS, where the cycle follows the line cycle.
+func prettyPrintLasso(prefix []ltlStep, cycle []ltlStep) string {
+	prettyPrintStep := func(step ltlStep) string {
+		if step.Transition == nil {
+			return "s" + strconv.Itoa(step.State) + "  deadlock"
+		}
+		return prettyPrintPath([]Transition{*step.Transition})
+	}
+	var lines []string
+	for _, step := range prefix {
+		lines = append(lines, prettyPrintStep(step))
+	}
+	lines = append(lines, "cycle")
+	for _, step := range cycle {
+		lines = append(lines, prettyPrintStep(step))
+	}
+	return strings.Join(lines, "\n")
+}
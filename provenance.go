@@ -0,0 +1,147 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// explainTransitions records the provenance of each transition in the LTS.
+var explainTransitions bool
+
+// Rule is a rule of the semantics by which a transition is derived.
+type Rule string
+
+const (
+	RuleInp2a Rule = "INP2A"
+	RuleInp2b Rule = "INP2B"
+	RuleOut   Rule = "OUT"
+	RuleOpen  Rule = "OPEN"
+	RuleRes   Rule = "RES"
+	RuleComm  Rule = "COMM"
+	RuleClose Rule = "CLOSE"
+	RuleRec   Rule = "REC"
+	RuleSum   Rule = "SUM"
+	RulePar   Rule = "PAR"
+	RuleMatch Rule = "MATCH"
+)
+
+// Provenance is the derivation of a transition: the rule concluding it, the
+// position and component of the prefix of an INP2A, INP2B or OUT axiom, and
+// the derivations of its premises.
+type Provenance struct {
+	Rule      Rule
+	Position  Position
+	Component string
+	Premises  []Provenance
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return "?"
+	}
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// String returns the derivation with the premises of each rule in brackets,
+// e.g., PAR(COMM(OUT 1:1, INP2A 1:12)).
+func (p Provenance) String() string {
+	if len(p.Premises) == 0 {
+		return string(p.Rule) + " " + p.Position.String()
+	}
+	premises := make([]string, len(p.Premises))
+	for i, premise := range p.Premises {
+		premises[i] = premise.String()
+	}
+	return string(p.Rule) + "(" + strings.Join(premises, ", ") + ")"
+}
+
+// Prefixes returns the positions of the prefixes which fired in the
+// derivation, from left to right.
+func (p Provenance) Prefixes() []Position {
+	var positions []Position
+	for _, axiom := range p.axioms() {
+		positions = append(positions, axiom.Position)
+	}
+	return positions
+}
+
+// axioms returns the INP2A, INP2B and OUT axioms of the derivation, from left
+// to right.
+func (p Provenance) axioms() []Provenance {
+	if len(p.Premises) == 0 {
+		return []Provenance{p}
+	}
+	var axioms []Provenance
+	for _, premise := range p.Premises {
+		axioms = append(axioms, premise.axioms()...)
+	}
+	return axioms
+}
+
+// derive returns the derivation of a rule with premises.
+func derive(rule Rule, premises ...Provenance) Provenance {
+	return Provenance{
+		Rule:     rule,
+		Premises: premises,
+	}
+}
+
+// addProvenance adds a derivation of a transition, unless it was recorded.
+func addProvenance(provenance map[Transition][]Provenance, trn Transition, p Provenance) {
+	str := p.String()
+	for _, q := range provenance[trn] {
+		if q.String() == str {
+			return
+		}
+	}
+	provenance[trn] = append(provenance[trn], p)
+}
+
+// getPrefixPositionsKey returns the positions of the prefixes of a process,
+// which distinguish the transitions of processes of different prefixes that
+// are otherwise equal.
+func getPrefixPositionsKey(elem Element) string {
+	var positions []string
+	walkPrefixes(elem, func(position Position, component *string) {
+		positions = append(positions, position.String())
+	})
+	return strings.Join(positions, ",")
+}
+
+// walkPrefixes calls visit with the position and component of each input and
+// output prefix of a process, from left to right, except those of processes
+// which are not unfolded.
+func walkPrefixes(elem Element, visit func(position Position, component *string)) {
+	switch elem := elem.(type) {
+	case *ElemOutput:
+		visit(elem.Position, &elem.Component)
+		walkPrefixes(elem.Next, visit)
+	case *ElemInput:
+		visit(elem.Position, &elem.Component)
+		walkPrefixes(elem.Next, visit)
+	case *ElemEquality:
+		walkPrefixes(elem.Next, visit)
+	case *ElemRestriction:
+		walkPrefixes(elem.Next, visit)
+	case *ElemSum:
+		walkPrefixes(elem.ProcessL, visit)
+		walkPrefixes(elem.ProcessR, visit)
+	case *ElemParallel:
+		walkPrefixes(elem.ProcessL, visit)
+		walkPrefixes(elem.ProcessR, visit)
+	case *ElemRoot:
+		walkPrefixes(elem.Next, visit)
+	}
+}
+
+// prettyPrintProvenance returns the derivations of a transition in order,
+// each with an indent and separated by a line separator.
+func prettyPrintProvenance(provenance []Provenance, indent string, sep string) string {
+	lines := make([]string, len(provenance))
+	for i, p := range provenance {
+		lines[i] = indent + p.String()
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, sep)
+}
@@ -0,0 +1,89 @@
+package pifra
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	tests := map[string]struct {
+		input      []byte
+		closed     bool
+		provenance []string
+	}{
+		"inputs": {
+			input:      []byte(`a(x).0`),
+			provenance: []string{"INP2A 1:1", "INP2B 1:1"},
+		},
+		"res": {
+			input:      []byte(`$n.a'<b>.n'<n>.0`),
+			provenance: []string{"RES(OUT 1:4)"},
+		},
+		"open": {
+			input:      []byte(`$n.(a'<n>.0 + b'<b>.0)`),
+			provenance: []string{"SUM(OPEN(OUT 1:5))", "SUM(OUT 1:15)"},
+		},
+		"rec_and_match": {
+			input: []byte(`P(a) = [a=a]a'<a>.0
+P(b)`),
+			provenance: []string{"REC(MATCH(OUT 1:13))"},
+		},
+		"comm": {
+			input: []byte(`a(x).0 |
+  a'<b>.0`),
+			closed:     true,
+			provenance: []string{"COMM(OUT 2:3, INP2A 1:1)"},
+		},
+		"close": {
+			input:      []byte(`a(x).0 | $n.a'<n>.0`),
+			closed:     true,
+			provenance: []string{"CLOSE(INP2B 1:1, OPEN(OUT 1:13))"},
+		},
+		"same_transition": {
+			input:      []byte(`a'<a>.0 | a'<a>.0`),
+			provenance: []string{"PAR(OUT 1:1)", "PAR(OUT 1:11)"},
+		},
+	}
+	maxStatesExplored = 1
+	registerSize = 1073741824
+	explainTransitions = true
+	defer func() {
+		maxStatesExplored = 100
+		closedSystem = false
+		explainTransitions = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			closedSystem = test.closed
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var provenance []string
+			for _, trn := range lts.Transitions {
+				for _, p := range lts.Provenance[trn] {
+					provenance = append(provenance, p.String())
+				}
+			}
+			sort.Strings(provenance)
+			if !reflect.DeepEqual(provenance, test.provenance) {
+				t.Errorf("got provenance %q, want %q", provenance, test.provenance)
+			}
+		})
+	}
+}
+
+func TestProvenancePrefixes(t *testing.T) {
+	p := derive(RulePar, derive(RuleClose,
+		Provenance{Rule: RuleInp2b, Position: Position{Line: 1, Column: 1}},
+		derive(RuleOpen, Provenance{Rule: RuleOut, Position: Position{Line: 2, Column: 5}})))
+	want := []Position{{Line: 1, Column: 1}, {Line: 2, Column: 5}}
+	if prefixes := p.Prefixes(); !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("got prefixes %v, want %v", prefixes, want)
+	}
+	if str := p.String(); str != "PAR(CLOSE(INP2B 1:1, OPEN(OUT 2:5)))" {
+		t.Errorf("got %s", str)
+	}
+}
@@ -0,0 +1,409 @@
+package pifra
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// replHelp describes the input of the REPL.
+var replHelp = `PROCESS          generate the LTS of a process with the definitions
+NAME(...) = ...  add or replace a definition
+:load FILE       add the definitions of a file, and generate the LTS of its process
+:defs            print the definitions
+:set             print the settings
+:set NAME VALUE  change a setting
+:show sN         print a state of the last LTS with its transitions
+:trace sN        print a shortest trace to a state of the last LTS
+:dot FILE        write the last LTS to a Graphviz DOT file
+:help            print this help
+:quit            quit, as does the end of the input
+A line ending with . | + = , or \ or with an unclosed bracket continues on the
+next line.`
+
+// replSettings are the settings of the REPL, which are the flags of the same
+// names.
+var replSettings = []string{"max-states", "max-registers", "disable-gc", "closed", "por", "symmetry"}
+
+// replHistory is the file in the home directory of the lines read by the REPL.
+var replHistory = ".pifra_history"
+
+// replHistorySize is the number of lines kept in the history file.
+var replHistorySize = 1000
+
+// repl is a read-eval-print loop of pi-calculus processes. Definitions
+// accumulate in the session, and each process is generated with them.
+type repl struct {
+	flags Flags
+	// The definitions by name, pretty-printed.
+	defs map[string]string
+	// The last LTS generated.
+	lts *Lts
+}
+
+// newRepl returns a REPL with the settings of the flags and no definitions.
+func newRepl(flags Flags) *repl {
+	return &repl{
+		flags: flags,
+		defs:  make(map[string]string),
+	}
+}
+
+// isIncomplete returns whether an input continues on the next line, i.e., it
+// ends with an operator or a backslash, or has an unclosed bracket.
+func isIncomplete(input string) bool {
+	trimmed := strings.TrimSpace(input)
+	if strings.HasPrefix(trimmed, ":") {
+		return false
+	}
+	if trimmed != "" && strings.ContainsAny(trimmed[len(trimmed)-1:], ".|+=,\\") {
+		return true
+	}
+	var depth int
+	for _, r := range trimmed {
+		switch r {
+		case '(', '<', '[':
+			depth++
+		case ')', '>', ']':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// parseStateId parses a state of an LTS written sN.
+func parseStateId(state string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(state, "s"))
+	if err != nil || !strings.HasPrefix(state, "s") || id < 0 {
+		return 0, fmt.Errorf("%q is not a state, e.g., s3", state)
+	}
+	return id, nil
+}
+
+// definitions returns the definitions of the session in name order.
+func (r *repl) definitions() []string {
+	var names []string
+	for name := range r.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var defs []string
+	for _, name := range names {
+		defs = append(defs, r.defs[name])
+	}
+	return defs
+}
+
+// program returns the definitions of the session followed by an input.
+func (r *repl) program(input string) []byte {
+	return []byte(strings.Join(append(r.definitions(), input), "\n"))
+}
+
+// eval adds the definitions of an input to the session, and generates the
+// LTS of its process, if any, with the definitions of the session.
+func (r *repl) eval(input string) (string, error) {
+	if err := parseProgram([]byte(input)); err != nil {
+		return "", err
+	}
+	if len(undeclaredProcs) > 1 {
+		return "", fmt.Errorf("there cannot be more than one undeclared processes")
+	}
+	defs := make(map[string]string)
+	for name, dp := range DeclaredProcs {
+		def := name
+		if len(dp.Parameters) != 0 {
+			def = def + "(" + strings.Join(dp.Parameters, ", ") + ")"
+		}
+		defs[name] = def + " = " + PrettyPrintAst(dp.Process)
+	}
+	hasProcess := len(undeclaredProcs) == 1
+
+	var output string
+	if hasProcess {
+		initFlags(r.flags)
+		lts, err := generateLts(r.program(input))
+		if err != nil {
+			return "", err
+		}
+		r.lts = &lts
+		output = string(generatePrettyLts(lts))
+	}
+	var names []string
+	for name, def := range defs {
+		r.defs[name] = def
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !hasProcess {
+		output = "defined " + strings.Join(names, ", ")
+	}
+	return output, nil
+}
+
+// set changes a setting, or prints the settings.
+func (r *repl) set(args []string) (string, error) {
+	if len(args) == 0 {
+		values := []string{
+			strconv.Itoa(r.flags.MaxStates),
+			strconv.Itoa(r.flags.RegisterSize),
+			strconv.FormatBool(r.flags.DisableGC),
+			strconv.FormatBool(r.flags.Closed),
+			strconv.FormatBool(r.flags.PartialOrder),
+			strconv.FormatBool(r.flags.Symmetry),
+		}
+		if r.flags.RegisterSize == 1073741824 {
+			values[1] = "0"
+		}
+		var lines []string
+		for i, name := range replSettings {
+			lines = append(lines, fmt.Sprintf("%-20s %s", name, values[i]))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	if len(args) != 2 {
+		return "", fmt.Errorf("setting and value required, e.g., :set max-states 100")
+	}
+	name, value := args[0], args[1]
+	flags := r.flags
+	switch name {
+	case "max-states", "max-registers":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		if name == "max-states" {
+			flags.MaxStates = n
+		} else {
+			// 0 is unlimited, as for the flag.
+			if n == 0 {
+				n = 1073741824
+			}
+			flags.RegisterSize = n
+		}
+	case "disable-gc", "closed", "por", "symmetry":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", name)
+		}
+		switch name {
+		case "disable-gc":
+			flags.DisableGC = b
+		case "closed":
+			flags.Closed = b
+		case "por":
+			flags.PartialOrder = b
+		case "symmetry":
+			flags.Symmetry = b
+		}
+	default:
+		return "", fmt.Errorf("unknown setting %s, one of %s", name, strings.Join(replSettings, ", "))
+	}
+	if err := ValidateFlags(flags, CommandLts); err != nil {
+		return "", err
+	}
+	r.flags = flags
+	return name + " = " + value, nil
+}
+
+// getState returns a state of the last LTS.
+func (r *repl) getState(args []string) (int, error) {
+	if r.lts == nil {
+		return 0, fmt.Errorf("no LTS generated")
+	}
+	if len(args) != 1 {
+		return 0, fmt.Errorf("state required, e.g., s3")
+	}
+	id, err := parseStateId(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := r.lts.States[id]; !ok {
+		return 0, fmt.Errorf("s%d is not a state of the last LTS", id)
+	}
+	return id, nil
+}
+
+// command runs a meta-command of the REPL, returning its output and whether
+// to quit.
+func (r *repl) command(input string) (string, bool, error) {
+	fields := strings.Fields(input)
+	args := fields[1:]
+	switch fields[0] {
+	case ":load":
+		if len(args) != 1 {
+			return "", false, fmt.Errorf("file required")
+		}
+		program, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return "", false, err
+		}
+		output, err := r.eval(string(program))
+		return output, false, err
+	case ":defs":
+		return strings.Join(r.definitions(), "\n"), false, nil
+	case ":set":
+		output, err := r.set(args)
+		return output, false, err
+	case ":show":
+		id, err := r.getState(args)
+		if err != nil {
+			return "", false, err
+		}
+		return prettyPrintLtsState(*r.lts, id), false, nil
+	case ":trace":
+		id, err := r.getState(args)
+		if err != nil {
+			return "", false, err
+		}
+		if id == 0 {
+			return "s0 is the root", false, nil
+		}
+		return prettyPrintPath(getPath(getShortestPaths(*r.lts), id)), false, nil
+	case ":dot":
+		if r.lts == nil {
+			return "", false, fmt.Errorf("no LTS generated")
+		}
+		if len(args) != 1 {
+			return "", false, fmt.Errorf("file required")
+		}
+		if err := writeFile(GenerateGraphVizFile(*r.lts, r.flags.GVOutputStates), args[0]); err != nil {
+			return "", false, err
+		}
+		return "LTS written to " + args[0], false, nil
+	case ":help":
+		return replHelp, false, nil
+	case ":quit":
+		return "", true, nil
+	}
+	return "", false, fmt.Errorf("unknown command %s, :help for help", fields[0])
+}
+
+// read runs a complete input of the REPL, either a meta-command or a
+// program, returning its output and whether to quit.
+func (r *repl) read(input string) (string, bool, error) {
+	input = strings.TrimSpace(strings.Replace(input, "\\\n", "\n", -1))
+	if input == "" {
+		return "", false, nil
+	}
+	if strings.HasPrefix(input, ":") {
+		return r.command(input)
+	}
+	output, err := r.eval(input)
+	return output, false, err
+}
+
+// prettyPrintLtsState returns a state of an LTS with its transitions, in the
+// format of the pretty-printed LTS.
+func prettyPrintLtsState(lts Lts, id int) string {
+	printState := func(id int) string {
+		mark := ""
+		if lts.RegSizeReached[id] {
+			mark = "+"
+		}
+		conf := lts.States[id]
+		return "s" + strconv.Itoa(id) + mark + " = " + PrettyPrintRegister(conf.Registers) +
+			" |- " + PrettyPrintAst(conf.Process)
+	}
+	str := printState(id)
+	if !lts.isExplored(id) {
+		return str + "\nunexplored"
+	}
+	for _, trn := range lts.Transitions {
+		if trn.Source == id {
+			str = str + "\ns" + strconv.Itoa(id) + "  " + PrettyPrintLabel(trn.Label) + "  " +
+				printState(trn.Destination)
+		}
+	}
+	return str
+}
+
+// loadHistory returns the lines of the history file, if any.
+func loadHistory() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	input, err := ioutil.ReadFile(filepath.Join(home, replHistory))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(input), "\n"), "\n")
+}
+
+// saveHistory writes the last lines of the history to the history file.
+func saveHistory(history []string) {
+	home, err := os.UserHomeDir()
+	if err != nil || len(history) == 0 {
+		return
+	}
+	if len(history) > replHistorySize {
+		history = history[len(history)-replHistorySize:]
+	}
+	ioutil.WriteFile(filepath.Join(home, replHistory), []byte(strings.Join(history, "\n")+"\n"), 0644)
+}
+
+// InteractiveMode runs a REPL in which definitions accumulate and the LTSs of
+// processes are generated, until the end of the input. The input file of the
+// flags is loaded first, if given.
+func InteractiveMode(flags Flags) error {
+	gvLayout = flags.GVLayout
+	outputPaths = flags.Paths
+	r := newRepl(flags)
+	fd := int(os.Stdin.Fd())
+	editor := newLineEditor(os.Stdin, os.Stdout, func() (func(), error) {
+		return makeRaw(fd)
+	})
+	editor.history = loadHistory()
+	// The history is only of the lines typed on a terminal.
+	defer func() {
+		if editor.edited {
+			saveHistory(editor.history)
+		}
+	}()
+
+	if flags.InputFile != "" {
+		output, _, err := r.read(":load " + flags.InputFile)
+		if err != nil {
+			return err
+		}
+		fmt.Println(output)
+	}
+
+	var input string
+	for {
+		prompt := "> "
+		if input != "" {
+			prompt = "... "
+		}
+		line, err := editor.readLine(prompt)
+		if err == errInterrupt {
+			input = ""
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		end := err == io.EOF
+		editor.addHistory(line)
+		input = input + line + "\n"
+		// An empty line or the end of the input ends an incomplete input.
+		if !end && line != "" && isIncomplete(input) {
+			continue
+		}
+		output, quit, err := r.read(input)
+		input = ""
+		if err != nil {
+			fmt.Println("error:", err)
+		} else if output != "" {
+			fmt.Println(output)
+		}
+		if quit || end {
+			return nil
+		}
+	}
+}
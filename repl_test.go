@@ -0,0 +1,169 @@
+package pifra
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	r := newRepl(Flags{
+		MaxStates:    100,
+		RegisterSize: 1073741824,
+	})
+	tests := []struct {
+		input  string
+		output string
+		err    bool
+	}{
+		{input: "P(a) = a'<a>.Q(a)", output: "defined P"},
+		{input: "Q(a) = a(x).0", output: "defined Q"},
+		{input: ":defs", output: "P(a) = a'<a>.Q(a)\nQ(a) = a(x).0"},
+		{input: ":show s0", err: true},
+		{input: "P(b)", output: "s0 = {(1,#1)} |- P(#1)\n" +
+			"s0  1'1   s1 = {(1,#1)} |- Q(#1)\n" +
+			"s1  1 1   s2 = {} |- 0\n" +
+			"s1  1 1*  s2 = {} |- 0"},
+		{input: ":show s1", output: "s1 = {(1,#1)} |- Q(#1)\n" +
+			"s1  1 1   s2 = {} |- 0\n" +
+			"s1  1 1*  s2 = {} |- 0"},
+		{input: ":trace s2", output: "s0  1'1   s1\ns1  1 1   s2"},
+		{input: ":trace s3", err: true},
+		{input: ":trace 2", err: true},
+		// A definition replaces the definition of the same name.
+		{input: "Q(a) = 0\nP(b)", output: "s0 = {(1,#1)} |- P(#1)\n" +
+			"s0  1'1   s1 = {} |- Q(#1)"},
+		{input: ":defs", output: "P(a) = a'<a>.Q(a)\nQ(a) = 0"},
+		{input: ":set max-states 1", output: "max-states = 1"},
+		{input: ":set max-states x", err: true},
+		{input: ":set colour true", err: true},
+		{input: ":set disable-gc true", output: "disable-gc = true"},
+		{input: "a(x).b(y).0", output: "s0 = {(1,#1),(2,#2)} |- #1(&1).#2(&2).0\n" +
+			"s0  1 1   s1 = {(1,#1),(2,#2)} |- #2(&1).0\n" +
+			"s0  1 2   s1 = {(1,#1),(2,#2)} |- #2(&1).0\n" +
+			"s0  1 1*  s1 = {(1,#1),(2,#2)} |- #2(&1).0"},
+		{input: ":show s1", output: "s1 = {(1,#1),(2,#2)} |- #2(&1).0\nunexplored"},
+		{input: "a(x", err: true},
+		{input: ":unknown", err: true},
+	}
+
+	for _, test := range tests {
+		output, quit, err := r.read(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("%q: got error %v, want error %t", test.input, err, test.err)
+			continue
+		}
+		if quit {
+			t.Errorf("%q: got quit", test.input)
+		}
+		if !test.err && output != test.output {
+			t.Errorf("%q: got output\n%s\nwant\n%s", test.input, output, test.output)
+		}
+	}
+	if _, quit, _ := r.read(":quit"); !quit {
+		t.Error("got no quit")
+	}
+}
+
+func TestReplSetValidated(t *testing.T) {
+	r := newRepl(Flags{
+		MaxStates:    100,
+		RegisterSize: 1073741824,
+		Find:         "_BAD",
+	})
+	if _, _, err := r.read(":set por true"); err == nil {
+		t.Error("got no error for --por with --find")
+	}
+	if r.flags.PartialOrder {
+		t.Error("got invalid setting applied")
+	}
+	if _, _, err := r.read(":set closed true"); err != nil {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := map[string]bool{
+		"a'<b>.0":           false,
+		"P(a) =":            true,
+		"a(x).":             true,
+		"a'<b>.0 |":         true,
+		"$x.(a'<x>.0":       true,
+		"a'<b>.0 \\":        true,
+		":load file.pi":     false,
+		"":                  false,
+		"[a=b]a'<b>.0 + 0 ": false,
+	}
+	for input, incomplete := range tests {
+		if got := isIncomplete(input); got != incomplete {
+			t.Errorf("%q: got incomplete %t, want %t", input, got, incomplete)
+		}
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	tests := map[string]struct {
+		history []string
+		keys    string
+		line    string
+		err     error
+	}{
+		"typed": {
+			keys: "a'<b>.0\r",
+			line: "a'<b>.0",
+		},
+		"moved_and_deleted": {
+			keys: "a'<b.0\x1b[D\x1b[D>\x01\x7f\x05\x081\r",
+			line: "a'<b>.1",
+		},
+		"killed": {
+			keys: "a(x).0 | b(y).0\x17\x17x\x01\x06\x0b\r",
+			line: "a",
+		},
+		"history": {
+			history: []string{"first", "second"},
+			keys:    "\x1b[A\x1b[A\x1b[B!\r",
+			line:    "second!",
+		},
+		"interrupted": {
+			keys: "abc\x03",
+			err:  errInterrupt,
+		},
+		"end_of_input": {
+			keys: "\x04",
+			err:  io.EOF,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			editor := newLineEditor(strings.NewReader(test.keys), &out, func() (func(), error) {
+				return func() {}, nil
+			})
+			editor.history = test.history
+			line, err := editor.readLine("> ")
+			if err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if line != test.line {
+				t.Errorf("got line %q, want %q", line, test.line)
+			}
+		})
+	}
+
+	// Without a terminal, lines are read as they are, up to the end of the
+	// input.
+	editor := newLineEditor(strings.NewReader("a'<b>.0\nlast"), &bytes.Buffer{}, nil)
+	for _, want := range []string{"a'<b>.0", "last"} {
+		if line, err := editor.readLine("> "); line != want || err != nil {
+			t.Errorf("got line %q and error %v, want %q", line, err, want)
+		}
+	}
+	if _, err := editor.readLine("> "); err != io.EOF {
+		t.Errorf("got error %v at the end of the input, want EOF", err)
+	}
+	if editor.edited {
+		t.Errorf("lines read without a terminal are kept in the history file")
+	}
+}
@@ -0,0 +1,134 @@
+package pifra
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// secretBinder is the restriction whose names are checked for secrecy, as
+// NAME or NAME@PROCESS. If set, exploration stops at the first transition
+// which extrudes a name created by the restriction to the environment.
+var secretBinder string
+
+// secretTag marks the names created by the secret restriction. It cannot occur
+// in the names of a model, and is kept by the normalisation of bound names.
+const secretTag = "@secret"
+
+// markSecretBinder renames the name of the restriction given by secretBinder,
+// in the undeclared process or in a declared process, so that the names it
+// creates carry secretTag.
+func markSecretBinder(proc Element) error {
+	name, location := secretBinder, ""
+	if i := strings.Index(secretBinder, "@"); i != -1 {
+		name, location = secretBinder[:i], secretBinder[i+1:]
+	}
+	where := "the undeclared process"
+	if location != "" {
+		dp, ok := DeclaredProcs[location]
+		if !ok {
+			return fmt.Errorf("process %s is not declared", location)
+		}
+		proc = dp.Process
+		where = location
+	}
+
+	binders := getRestrictions(proc, name)
+	if len(binders) == 0 {
+		return fmt.Errorf("no restriction of %s in %s", name, where)
+	}
+	if len(binders) > 1 {
+		return fmt.Errorf("%d restrictions of %s in %s", len(binders), name, where)
+	}
+	resElem := binders[0]
+	oldName := resElem.Restrict.Name
+	newName := strings.Replace(oldName, name, name+secretTag, 1)
+	resElem.Restrict.Name = newName
+	subBoundNames(resElem.Next, oldName, newName)
+	return nil
+}
+
+// getRestrictions returns the restrictions of a name in a process, where the
+// name of a restriction of the undeclared process has been alpha-converted.
+func getRestrictions(elem Element, name string) []*ElemRestriction {
+	var binders []*ElemRestriction
+	var walk func(elem Element)
+	walk = func(elem Element) {
+		switch elem.Type() {
+		case ElemTypOutput:
+			walk(elem.(*ElemOutput).Next)
+		case ElemTypInput:
+			walk(elem.(*ElemInput).Next)
+		case ElemTypMatch:
+			walk(elem.(*ElemEquality).Next)
+		case ElemTypRestriction:
+			resElem := elem.(*ElemRestriction)
+			resName := resElem.Restrict.Name
+			if resName == name {
+				binders = append(binders, resElem)
+			} else if strings.HasPrefix(resName, bnPrefix+name+"_") {
+				if _, err := strconv.Atoi(strings.TrimPrefix(resName, bnPrefix+name+"_")); err == nil {
+					binders = append(binders, resElem)
+				}
+			}
+			walk(resElem.Next)
+		case ElemTypSum:
+			sumElem := elem.(*ElemSum)
+			walk(sumElem.ProcessL)
+			walk(sumElem.ProcessR)
+		case ElemTypParallel:
+			parElem := elem.(*ElemParallel)
+			walk(parElem.ProcessL)
+			walk(parElem.ProcessR)
+		case ElemTypRoot:
+			walk(elem.(*ElemRoot).Next)
+		}
+	}
+	walk(elem)
+	return binders
+}
+
+// isSecretLeak returns whether a configuration, before normalisation, is
+// reached by a fresh output of a name created by the secret restriction, i.e.,
+// by an OPEN step extruding it to the environment.
+func isSecretLeak(conf Configuration) bool {
+	if secretBinder == "" || conf.Label.Symbol2.Type != SymbolTypFreshOutput {
+		return false
+	}
+	return strings.Contains(conf.Registers.GetName(conf.Label.Symbol2.Value), secretTag)
+}
+
+// generateSecretReport returns a shortest trace to the first transition
+// leaking the secret, or whether it is kept secret within the bounds of
+// exploration.
+func generateSecretReport(lts Lts) []byte {
+	var buffer bytes.Buffer
+	if lts.Leaked == nil {
+		var unexplored int
+		for id := range lts.States {
+			if !lts.isExplored(id) {
+				unexplored++
+			}
+		}
+		if unexplored == 0 {
+			buffer.WriteString(secretBinder + " is secret")
+		} else {
+			buffer.WriteString(secretBinder + " is secret within the bounds\n")
+			buffer.WriteString("states unexplored    " + strconv.Itoa(unexplored))
+		}
+		return buffer.Bytes()
+	}
+
+	path := append(getPath(getShortestPaths(lts), lts.Leaked.Source), *lts.Leaked)
+	steps := " steps"
+	if len(path) == 1 {
+		steps = " step"
+	}
+	buffer.WriteString(secretBinder + " is leaked in " + strconv.Itoa(len(path)) + steps)
+	for i, label := range getNamedTrace(lts, path) {
+		buffer.WriteString("\ns" + strconv.Itoa(path[i].Source) + "  " + label +
+			"  s" + strconv.Itoa(path[i].Destination))
+	}
+	return buffer.Bytes()
+}
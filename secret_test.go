@@ -0,0 +1,112 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	tests := map[string]struct {
+		input  []byte
+		secret string
+		trace  []string
+		trns   int
+	}{
+		"extruded": {
+			input:  []byte(`$s.a'<s>.0`),
+			secret: "s",
+			trace:  []string{"a'#1^"},
+		},
+		"extruded_after_communication": {
+			input:  []byte(`$c.($s.c'<s>.0 | c(x).a'<x>.0)`),
+			secret: "s",
+			trace:  []string{"t", "a'#1^"},
+		},
+		"source_state_explored": {
+			input:  []byte(`$s.a'<s>.0 | _B(x).0`),
+			secret: "s",
+			trace:  []string{"a'#1^"},
+			trns:   4,
+		},
+		"communicated_only": {
+			input:  []byte(`$s.$c.(c'<s>.0 | c(x).a'<a>.0)`),
+			secret: "s",
+		},
+		"other_restriction_extruded": {
+			input:  []byte(`$s.$t.(a'<t>.0 | s(x).0)`),
+			secret: "s",
+		},
+		"declared_process": {
+			input:  []byte(`P(a) = $s.a'<s>.0` + "\n" + `b(x).P(x)`),
+			secret: "s@P",
+			trace:  []string{"b b", "b'#1^"},
+		},
+		"shadowed": {
+			input:  []byte(`P(a) = $s.a(s).a'<s>.0` + "\n" + `P(a)`),
+			secret: "s@P",
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		secretBinder = ""
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secretBinder = test.secret
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lts.Leaked == nil {
+				if test.trace != nil {
+					t.Fatalf("%s not leaked", test.secret)
+				}
+				return
+			}
+			path := append(getPath(getShortestPaths(lts), lts.Leaked.Source), *lts.Leaked)
+			trace := getNamedTrace(lts, path)
+			if !reflect.DeepEqual(trace, test.trace) {
+				t.Errorf("got trace %v, want %v", trace, test.trace)
+			}
+			if test.trns != 0 && len(lts.Transitions) != test.trns {
+				t.Errorf("got %d transitions, want %d", len(lts.Transitions), test.trns)
+			}
+		})
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	tests := map[string]struct {
+		input  []byte
+		secret string
+	}{
+		"undeclared_process": {
+			input:  []byte(`$s.a'<s>.0`),
+			secret: "s@P",
+		},
+		"no_restriction": {
+			input:  []byte(`$s.a'<s>.0`),
+			secret: "t",
+		},
+		"ambiguous": {
+			input:  []byte(`$s.a'<s>.0 | $s.b'<s>.0`),
+			secret: "s",
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		secretBinder = ""
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secretBinder = test.secret
+			if _, err := generateLts(test.input); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
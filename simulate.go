@@ -0,0 +1,253 @@
+package pifra
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Label types which may be weighted in random walks.
+const (
+	WeightTau         = "tau"
+	WeightInput       = "input"
+	WeightFreshInput  = "fresh-input"
+	WeightOutput      = "output"
+	WeightFreshOutput = "fresh-output"
+)
+
+// Reasons for the end of a random walk.
+const (
+	walkTerminated = "terminated"
+	walkDeadlock   = "deadlock"
+	walkUnweighted = "no weighted transitions"
+	walkLength     = "length bound"
+	walkRegisters  = "register bound"
+)
+
+// walk is the path of a random walk from the root, with the reason it ended.
+type walk struct {
+	Path []Transition
+	End  string
+}
+
+// markedHit is the first transition on a marked name in the random walks: its
+// depth, i.e., the number of transitions up to and including it, and the walk
+// in which it was taken, with the number of walks taking a transition on the
+// name.
+type markedHit struct {
+	Depth int
+	Walk  int
+	Walks int
+}
+
+// simulation is the result of random walks from the root of a model. The
+// states are numbered in the order they were first reached.
+type simulation struct {
+	Walks  []walk
+	States map[int]Configuration
+	Marked map[string]markedHit
+}
+
+// parseWeights parses the weights of label types, e.g., "tau=2,input=0".
+// Label types which are not given have weight 1.
+func parseWeights(input string) (map[string]int, error) {
+	weights := map[string]int{
+		WeightTau:         1,
+		WeightInput:       1,
+		WeightFreshInput:  1,
+		WeightOutput:      1,
+		WeightFreshOutput: 1,
+	}
+	if input == "" {
+		return weights, nil
+	}
+	for _, field := range strings.Split(input, ",") {
+		pair := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("weight %q is not of the form type=weight", field)
+		}
+		if _, ok := weights[pair[0]]; !ok {
+			return nil, fmt.Errorf("label type %q must be tau, input, fresh-input, output or fresh-output", pair[0])
+		}
+		weight, err := strconv.Atoi(pair[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative integer", pair[0])
+		}
+		weights[pair[0]] = weight
+	}
+	return weights, nil
+}
+
+// getLabelWeightType returns the label type of a label for its weight.
+func getLabelWeightType(label Label) string {
+	switch {
+	case label.Symbol.Type == SymbolTypTau:
+		return WeightTau
+	case label.Symbol2.Type == SymbolTypFreshInput:
+		return WeightFreshInput
+	case label.Symbol2.Type == SymbolTypFreshOutput:
+		return WeightFreshOutput
+	case label.Symbol.Type == SymbolTypOutput:
+		return WeightOutput
+	default:
+		return WeightInput
+	}
+}
+
+// getSuccessors returns the normalised configurations reached by the
+// transitions of a state, as they are explored.
+func getSuccessors(state Configuration) []Configuration {
+	confs := restrictEnvironment(state, trans(state))
+	if closedSystem {
+		confs = filterClosedConfs(confs)
+	}
+	var succs []Configuration
+	for _, conf := range confs {
+		applyStructrualCongruence(conf)
+		fitRegister(&conf)
+		updateKnowledge(state, &conf)
+		succs = append(succs, conf)
+	}
+	return succs
+}
+
+// simulate takes random walks of at most a length from the root, choosing
+// each transition with a probability proportional to the weight of its label
+// type. The walks are reproducible from the seed.
+func simulate(root Configuration, walks int, length int, seed int64, weights map[string]int) simulation {
+	initTransCache()
+	defer func() {
+		transCache = nil
+	}()
+	random := rand.New(rand.NewSource(seed))
+
+	applyStructrualCongruence(root)
+	root = initKnowledge(root)
+	ids := map[string]int{getConfigurationKey(root): 0}
+	states := map[int]Configuration{0: root}
+	marked := make(map[string]markedHit)
+
+	var sim simulation
+	for i := 0; i < walks; i++ {
+		state := root
+		var path []Transition
+		hit := make(map[string]bool)
+		end := walkLength
+		for len(path) < length {
+			if len(state.Registers.Registers) > registerSize {
+				end = walkRegisters
+				break
+			}
+			succs := getSuccessors(state)
+			if len(succs) == 0 {
+				end = walkDeadlock
+				if isTerminated(state) {
+					end = walkTerminated
+				}
+				break
+			}
+			var total int
+			for _, succ := range succs {
+				total = total + weights[getLabelWeightType(succ.Label)]
+			}
+			if total == 0 {
+				end = walkUnweighted
+				break
+			}
+			choice := random.Intn(total)
+			var next Configuration
+			for _, succ := range succs {
+				choice = choice - weights[getLabelWeightType(succ.Label)]
+				if choice < 0 {
+					next = succ
+					break
+				}
+			}
+
+			key := getConfigurationKey(next)
+			if _, ok := ids[key]; !ok {
+				ids[key] = len(ids)
+				states[ids[key]] = next
+			}
+			trn := Transition{
+				Source:      ids[getConfigurationKey(state)],
+				Destination: ids[key],
+				Label:       next.Label,
+			}
+			path = append(path, trn)
+
+			if next.Label.Symbol.Type != SymbolTypTau {
+				name := state.Registers.GetName(next.Label.Symbol.Value)
+				if strings.HasPrefix(name, "_") && !hit[name] {
+					hit[name] = true
+					first, ok := marked[name]
+					if !ok || len(path) < first.Depth {
+						first.Depth = len(path)
+						first.Walk = i + 1
+					}
+					first.Walks++
+					marked[name] = first
+				}
+			}
+			state = next
+		}
+		sim.Walks = append(sim.Walks, walk{Path: path, End: end})
+	}
+	sim.States = states
+	sim.Marked = marked
+	return sim
+}
+
+// generateSimulationReport returns the number of distinct states reached by
+// the random walks, the first hit of each marked name, and the trace of each
+// walk unless omitted.
+func generateSimulationReport(sim simulation, freeNamesMap map[string]string, traces bool) []byte {
+	lts := Lts{
+		States:       sim.States,
+		FreeNamesMap: freeNamesMap,
+	}
+	var buffer bytes.Buffer
+	if traces {
+		for i, walk := range sim.Walks {
+			buffer.WriteString("walk " + strconv.Itoa(i+1) + "  ")
+			if labels := getNamedTrace(lts, walk.Path); len(labels) != 0 {
+				buffer.WriteString(strings.Join(labels, ", ") + "  ")
+			}
+			buffer.WriteString("(" + walk.End + ")\n")
+		}
+		buffer.WriteString("\n")
+	}
+
+	var steps int
+	ends := make(map[string]int)
+	for _, walk := range sim.Walks {
+		steps = steps + len(walk.Path)
+		ends[walk.End]++
+	}
+	buffer.WriteString("walks                " + strconv.Itoa(len(sim.Walks)) + "\n")
+	buffer.WriteString("steps                " + strconv.Itoa(steps) + "\n")
+	buffer.WriteString("states distinct      " + strconv.Itoa(len(sim.States)) + "\n")
+	buffer.WriteString("walks terminated     " + strconv.Itoa(ends[walkTerminated]) + "\n")
+	buffer.WriteString("walks deadlocked     " + strconv.Itoa(ends[walkDeadlock]) + "\n")
+	buffer.WriteString("walks unweighted     " + strconv.Itoa(ends[walkUnweighted]) + "\n")
+	buffer.WriteString("walks bounded        " + strconv.Itoa(ends[walkLength]+ends[walkRegisters]))
+
+	var names []string
+	for name := range sim.Marked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 {
+			buffer.WriteString("\n")
+		}
+		hit := sim.Marked[name]
+		buffer.WriteString("\n" + name + " first hit at depth " + strconv.Itoa(hit.Depth) +
+			" in walk " + strconv.Itoa(hit.Walk) + ", hit in " + strconv.Itoa(hit.Walks) + " of " +
+			strconv.Itoa(len(sim.Walks)) + " walks")
+	}
+	return buffer.Bytes()
+}
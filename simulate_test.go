@@ -0,0 +1,125 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	tests := map[string]struct {
+		input   []byte
+		length  int
+		weights string
+		ends    map[string]int
+		states  int
+		marked  map[string]markedHit
+	}{
+		"terminated": {
+			input:  []byte(`$a.(a'<a>.0 | a(x).0)`),
+			length: 10,
+			ends:   map[string]int{walkTerminated: 5},
+			states: 2,
+		},
+		"deadlocked": {
+			input:  []byte(`$a.a'<a>.0`),
+			length: 10,
+			ends:   map[string]int{walkDeadlock: 5},
+			states: 1,
+		},
+		"length_bound": {
+			input: []byte(`P(a) = a'<a>.P(a)
+P(a)`),
+			length: 3,
+			ends:   map[string]int{walkLength: 5},
+			states: 1,
+		},
+		"unweighted": {
+			input:   []byte(`a'<a>.0`),
+			length:  10,
+			weights: "output=0",
+			ends:    map[string]int{walkUnweighted: 5},
+			states:  1,
+		},
+		"marked_first_hit": {
+			input:   []byte(`$c.(c'<c>.0 | c(x).a'<a>._BAD'<a>.0)`),
+			length:  10,
+			weights: "input=0,fresh-input=0",
+			ends:    map[string]int{walkTerminated: 5},
+			states:  4,
+			marked:  map[string]markedHit{"_BAD": {Depth: 3, Walk: 1, Walks: 5}},
+		},
+	}
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			weights, err := parseWeights(test.weights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proc, err := InitProgram(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, _ := newRootConf(proc)
+			sim := simulate(root, 5, test.length, 1, weights)
+			ends := make(map[string]int)
+			for _, walk := range sim.Walks {
+				if len(walk.Path) > test.length {
+					t.Errorf("got walk of length %d, want at most %d", len(walk.Path), test.length)
+				}
+				ends[walk.End]++
+			}
+			if !reflect.DeepEqual(ends, test.ends) {
+				t.Errorf("got walk ends %v, want %v", ends, test.ends)
+			}
+			if len(sim.States) != test.states {
+				t.Errorf("got %d states, want %d", len(sim.States), test.states)
+			}
+			if len(sim.Marked) != 0 || len(test.marked) != 0 {
+				if !reflect.DeepEqual(sim.Marked, test.marked) {
+					t.Errorf("got marked hits %v, want %v", sim.Marked, test.marked)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateSeed(t *testing.T) {
+	input := []byte(`P(a) = a(x).(x'<x>.0 | P(a)) + a'<a>._BAD'<a>.0
+$b.(P(c) | c'<b>.0)`)
+	registerSize = 1073741824
+	weights, _ := parseWeights("")
+
+	var traces [3][]string
+	for i, seed := range []int64{1, 1, 2} {
+		proc, err := InitProgram(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, namesMap := newRootConf(proc)
+		sim := simulate(root, 20, 20, seed, weights)
+		traces[i] = append(traces[i], string(generateSimulationReport(sim, namesMap, true)))
+	}
+	if !reflect.DeepEqual(traces[0], traces[1]) {
+		t.Error("got different walks from the same seed")
+	}
+	if reflect.DeepEqual(traces[0], traces[2]) {
+		t.Error("got the same walks from different seeds")
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := parseWeights("tau=3, fresh-input=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weights[WeightTau] != 3 || weights[WeightFreshInput] != 0 || weights[WeightOutput] != 1 {
+		t.Errorf("got weights %v", weights)
+	}
+	for _, input := range []string{"tau", "silent=1", "tau=-1", "tau=x"} {
+		if _, err := parseWeights(input); err == nil {
+			t.Errorf("got no error for weights %q", input)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package pifra
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stepHelp describes the commands of the interactive simulator.
+var stepHelp = `N          fire the transition numbered N
+b [N]      backtrack N transitions (default 1)
+g sN|MARK  go to a state reached in the session, or a bookmark
+m MARK     bookmark the current state
+marks      list the bookmarks
+t          print the trace of the session
+w FILE     write the trace to a file
+r FILE     replay a trace from a file
+h          print this help
+q          quit`
+
+// stepper is an interactive simulator of a model. The states are numbered in
+// the order they were first enabled, and each is reached again by the trace by
+// which it was first reached.
+type stepper struct {
+	ids    map[string]int
+	states map[int]Configuration
+	traces map[int][]Transition
+	marks  map[string]int
+
+	// The current state, the trace by which it was reached and its enabled
+	// transitions.
+	state int
+	trace []Transition
+	succs []Transition
+}
+
+// newStepper returns an interactive simulator at the root of a model.
+func newStepper(root Configuration) *stepper {
+	applyStructrualCongruence(root)
+	root = initKnowledge(root)
+	s := &stepper{
+		ids:    map[string]int{getConfigurationKey(root): 0},
+		states: map[int]Configuration{0: root},
+		traces: map[int][]Transition{0: nil},
+		marks:  make(map[string]int),
+	}
+	s.enable()
+	return s
+}
+
+// enable numbers the transitions of the current state, and the states they
+// reach.
+func (s *stepper) enable() {
+	s.succs = nil
+	state := s.states[s.state]
+	if len(state.Registers.Registers) > registerSize {
+		return
+	}
+	for _, succ := range getSuccessors(state) {
+		key := getConfigurationKey(succ)
+		id, ok := s.ids[key]
+		if !ok {
+			id = len(s.ids)
+			s.ids[key] = id
+			s.states[id] = succ
+		}
+		trn := Transition{
+			Source:      s.state,
+			Destination: id,
+			Label:       succ.Label,
+		}
+		if !ok {
+			s.traces[id] = append(append([]Transition{}, s.trace...), trn)
+		}
+		s.succs = append(s.succs, trn)
+	}
+}
+
+// fire takes the enabled transition numbered from 1.
+func (s *stepper) fire(n int) error {
+	if n < 1 || n > len(s.succs) {
+		return fmt.Errorf("no transition %d from s%d", n, s.state)
+	}
+	trn := s.succs[n-1]
+	s.trace = append(s.trace, trn)
+	s.state = trn.Destination
+	s.enable()
+	return nil
+}
+
+// back undoes the last transitions of the trace.
+func (s *stepper) back(n int) error {
+	if n < 1 || n > len(s.trace) {
+		return fmt.Errorf("cannot backtrack %d of %d transitions", n, len(s.trace))
+	}
+	s.trace = s.trace[:len(s.trace)-n]
+	s.state = 0
+	if len(s.trace) != 0 {
+		s.state = s.trace[len(s.trace)-1].Destination
+	}
+	s.enable()
+	return nil
+}
+
+// jump goes to a state reached in the session, by the trace by which it was
+// first reached.
+func (s *stepper) jump(target string) error {
+	id, ok := s.marks[target]
+	if !ok {
+		var err error
+		if id, err = parseStateId(target); err != nil {
+			return fmt.Errorf("%s is neither a state nor a bookmark", target)
+		}
+		if _, ok := s.states[id]; !ok {
+			return fmt.Errorf("s%d has not been reached", id)
+		}
+	}
+	s.trace = append([]Transition{}, s.traces[id]...)
+	s.state = id
+	s.enable()
+	return nil
+}
+
+// replay fires in turn the transitions with the pretty-printed labels of a
+// trace, from the current state.
+func (s *stepper) replay(labels []string) error {
+	for i, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		n := 0
+		for j, trn := range s.succs {
+			if strings.TrimSpace(PrettyPrintLabel(trn.Label)) == label {
+				n = j + 1
+				break
+			}
+		}
+		if n == 0 {
+			return fmt.Errorf("line %d: no transition %s from s%d", i+1, label, s.state)
+		}
+		s.fire(n)
+	}
+	return nil
+}
+
+// prettyPrintTrace returns the pretty-printed labels of the trace, a label
+// per line, as read by replay.
+func (s *stepper) prettyPrintTrace() string {
+	var labels []string
+	for _, trn := range s.trace {
+		labels = append(labels, strings.TrimSpace(PrettyPrintLabel(trn.Label)))
+	}
+	return strings.Join(labels, "\n")
+}
+
+// prettyPrintState returns the current state with its enabled transitions
+// numbered.
+func (s *stepper) prettyPrintState() string {
+	state := s.states[s.state]
+	str := "s" + strconv.Itoa(s.state) + " = " + PrettyPrintRegister(state.Registers) +
+		" |- " + PrettyPrintAst(state.Process)
+	if len(state.Registers.Registers) > registerSize {
+		return str + "\nregister size reached"
+	}
+	if len(s.succs) == 0 {
+		if isTerminated(state) {
+			return str + "\nterminated"
+		}
+		return str + "\ndeadlock"
+	}
+	for i, trn := range s.succs {
+		dst := s.states[trn.Destination]
+		str = str + "\n" + fmt.Sprintf("%3d", i+1) + "  " + PrettyPrintLabel(trn.Label) +
+			"  s" + strconv.Itoa(trn.Destination) + " = " + PrettyPrintRegister(dst.Registers) +
+			" |- " + PrettyPrintAst(dst.Process)
+	}
+	return str
+}
+
+// command runs a command of the interactive simulator, returning its output
+// and whether to quit.
+func (s *stepper) command(line string) (string, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return s.prettyPrintState(), false, nil
+	}
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	if n, err := strconv.Atoi(fields[0]); err == nil {
+		if err := s.fire(n); err != nil {
+			return "", false, err
+		}
+		return s.prettyPrintState(), false, nil
+	}
+
+	switch fields[0] {
+	case "b":
+		n := 1
+		if arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil {
+				return "", false, fmt.Errorf("%s is not a number of transitions", arg)
+			}
+		}
+		if err := s.back(n); err != nil {
+			return "", false, err
+		}
+		return s.prettyPrintState(), false, nil
+	case "g":
+		if err := s.jump(arg); err != nil {
+			return "", false, err
+		}
+		return s.prettyPrintState(), false, nil
+	case "m":
+		if _, err := parseStateId(arg); arg == "" || err == nil {
+			return "", false, fmt.Errorf("bookmark name required, other than a state")
+		}
+		s.marks[arg] = s.state
+		return arg + " = s" + strconv.Itoa(s.state), false, nil
+	case "marks":
+		var names []string
+		for name := range s.marks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var lines []string
+		for _, name := range names {
+			lines = append(lines, name+" = s"+strconv.Itoa(s.marks[name]))
+		}
+		return strings.Join(lines, "\n"), false, nil
+	case "t":
+		return prettyPrintPath(s.trace), false, nil
+	case "w":
+		if arg == "" {
+			return "", false, fmt.Errorf("file required")
+		}
+		if err := writeFile([]byte(s.prettyPrintTrace()+"\n"), arg); err != nil {
+			return "", false, err
+		}
+		return "trace of " + strconv.Itoa(len(s.trace)) + " transitions written to " + arg, false, nil
+	case "r":
+		if arg == "" {
+			return "", false, fmt.Errorf("file required")
+		}
+		input, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return "", false, err
+		}
+		err = s.replay(strings.Split(string(input), "\n"))
+		return s.prettyPrintState(), false, err
+	case "h":
+		return stepHelp, false, nil
+	case "q":
+		return "", true, nil
+	}
+	return "", false, fmt.Errorf("unknown command %s, h for help", fields[0])
+}
+
+// StepMode simulates a pi-calculus program file interactively, firing the
+// transitions chosen by the user from a prompt. The trace file of the flags
+// is replayed first, if given.
+func StepMode(flags Flags, file string) error {
+	initFlags(flags)
+
+	program, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	proc, err := InitProgram(program)
+	if err != nil {
+		return err
+	}
+	root, _ := newRootConf(proc)
+	if evictRegisters && len(root.Registers.Registers) > registerSize {
+		return fmt.Errorf("the %d free names of the model exceed the %d registers",
+			len(root.Registers.Registers), registerSize)
+	}
+
+	initTransCache()
+	defer func() {
+		transCache = nil
+	}()
+	s := newStepper(root)
+	if flags.Replay != "" {
+		output, _, err := s.command("r " + flags.Replay)
+		if err != nil {
+			return err
+		}
+		fmt.Println(output)
+	} else {
+		fmt.Println(s.prettyPrintState())
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		output, quit, err := s.command(scanner.Text())
+		if err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		if quit {
+			return nil
+		}
+		if output != "" {
+			fmt.Println(output)
+		}
+	}
+}
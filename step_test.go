@@ -0,0 +1,82 @@
+package pifra
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStepper(t *testing.T) {
+	input := []byte(`a(x).(x'<x>.0 | a'<b>.0)`)
+	registerSize = 1073741824
+	proc, err := InitProgram(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := newRootConf(proc)
+	initTransCache()
+	defer func() {
+		transCache = nil
+	}()
+	s := newStepper(root)
+
+	// The inputs of a, b and a fresh name.
+	if len(s.succs) != 3 {
+		t.Fatalf("got %d transitions from the root, want 3", len(s.succs))
+	}
+	for _, line := range []string{"1", "m sent", "2"} {
+		if _, _, err := s.command(line); err != nil {
+			t.Fatalf("%s: %s", line, err)
+		}
+	}
+	if got := s.prettyPrintTrace(); got != "1 1\n1'2" {
+		t.Errorf("got trace %q, want %q", got, "1 1\n1'2")
+	}
+	sent := s.marks["sent"]
+
+	if _, _, err := s.command("b 3"); err == nil {
+		t.Error("got no error backtracking past the root")
+	}
+	if _, _, err := s.command("b"); err != nil {
+		t.Fatal(err)
+	}
+	if s.state != sent || len(s.trace) != 1 {
+		t.Errorf("got s%d after %d transitions, want s%d after 1", s.state, len(s.trace), sent)
+	}
+
+	// A state enabled but not fired is reached by the trace to its source.
+	if _, _, err := s.command("g s0"); err != nil {
+		t.Fatal(err)
+	}
+	fresh := s.succs[2].Destination
+	if _, _, err := s.command("g sent"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.command("g s" + strconv.Itoa(fresh)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.trace) != 1 || !strings.HasSuffix(PrettyPrintLabel(s.trace[0].Label), "*") {
+		t.Errorf("got trace %q to s%d, want a fresh input", s.prettyPrintTrace(), fresh)
+	}
+	for _, line := range []string{"g s99", "g none", "m s1", "0", "x"} {
+		if _, _, err := s.command(line); err == nil {
+			t.Errorf("%s: got no error", line)
+		}
+	}
+
+	if _, _, err := s.command("g s0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.replay([]string{"1 1", "", "1'1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.prettyPrintTrace(); got != "1 1\n1'1" {
+		t.Errorf("got trace %q after replay, want %q", got, "1 1\n1'1")
+	}
+	if err := s.replay([]string{"1 1"}); err == nil {
+		t.Error("got no error replaying a transition not enabled")
+	}
+	if _, quit, _ := s.command("q"); !quit {
+		t.Error("got no quit")
+	}
+}
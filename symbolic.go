@@ -0,0 +1,483 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// symbolicNames explores with symbolic semantics: placeholders are never
+// instantiated, and transitions carry constraints on the register.
+var symbolicNames bool
+
+// Constraint is a conjunction of equalities and inequalities of names, under
+// which a transition is enabled in symbolic semantics. In the LTS, it relates
+// the register labels of the source, e.g., "1=3,2!=3". While the transitions of
+// a state are computed, it relates names. The empty constraint is true.
+type Constraint string
+
+type constraintAtom struct {
+	Left       string
+	Right      string
+	Inequality bool
+}
+
+func (atom constraintAtom) String() string {
+	if atom.Inequality {
+		return atom.Left + "!=" + atom.Right
+	}
+	return atom.Left + "=" + atom.Right
+}
+
+func (c Constraint) atoms() []constraintAtom {
+	if c == "" {
+		return nil
+	}
+	var atoms []constraintAtom
+	for _, str := range strings.Split(string(c), ",") {
+		if i := strings.Index(str, "!="); i != -1 {
+			atoms = append(atoms, constraintAtom{
+				Left:       str[:i],
+				Right:      str[i+2:],
+				Inequality: true,
+			})
+		} else {
+			i := strings.Index(str, "=")
+			atoms = append(atoms, constraintAtom{
+				Left:  str[:i],
+				Right: str[i+1:],
+			})
+		}
+	}
+	return atoms
+}
+
+// lessName orders labels numerically and names lexicographically.
+func lessName(a string, b string) bool {
+	i, errA := strconv.Atoi(a)
+	j, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return i < j
+	}
+	return a < b
+}
+
+// newConstraint returns the conjunction of atoms in a canonical form.
+func newConstraint(atoms []constraintAtom) Constraint {
+	seen := make(map[constraintAtom]bool)
+	var canonical []constraintAtom
+	for _, atom := range atoms {
+		if lessName(atom.Right, atom.Left) {
+			atom.Left, atom.Right = atom.Right, atom.Left
+		}
+		if !seen[atom] {
+			seen[atom] = true
+			canonical = append(canonical, atom)
+		}
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		a, b := canonical[i], canonical[j]
+		if a.Left != b.Left {
+			return lessName(a.Left, b.Left)
+		}
+		if a.Right != b.Right {
+			return lessName(a.Right, b.Right)
+		}
+		return !a.Inequality && b.Inequality
+	})
+	var strs []string
+	for _, atom := range canonical {
+		strs = append(strs, atom.String())
+	}
+	return Constraint(strings.Join(strs, ","))
+}
+
+// conjoinConstraint returns the conjunction of a constraint over names with
+// atoms, and whether it is satisfiable. Atoms which hold or fail regardless of
+// the placeholders are simplified: distinct names which are not placeholders
+// are never equal.
+func conjoinConstraint(c Constraint, atoms ...constraintAtom) (Constraint, bool) {
+	all := c.atoms()
+	for _, atom := range atoms {
+		if atom.Left == atom.Right {
+			if atom.Inequality {
+				return "", false
+			}
+			continue
+		}
+		if !isPlaceholder(atom.Left) && !isPlaceholder(atom.Right) {
+			if !atom.Inequality {
+				return "", false
+			}
+			continue
+		}
+		all = append(all, atom)
+	}
+
+	// The classes of names equal by the constraint must not hold two distinct
+	// names which are not placeholders, nor the names of an inequality.
+	class := make(map[string]string)
+	var find func(name string) string
+	find = func(name string) string {
+		if parent, ok := class[name]; ok && parent != name {
+			root := find(parent)
+			class[name] = root
+			return root
+		}
+		return name
+	}
+	for _, atom := range all {
+		if !atom.Inequality {
+			class[find(atom.Left)] = find(atom.Right)
+		}
+	}
+	concrete := make(map[string]string)
+	for name := range class {
+		if isPlaceholder(name) {
+			continue
+		}
+		root := find(name)
+		if other, ok := concrete[root]; ok && other != name {
+			return "", false
+		}
+		concrete[root] = name
+	}
+	for _, atom := range all {
+		if atom.Inequality && find(atom.Left) == find(atom.Right) {
+			return "", false
+		}
+	}
+	return newConstraint(all), true
+}
+
+// restrictConstraint returns a constraint over names with a restricted name,
+// and whether it is satisfiable. The placeholders were received before the
+// restricted name was created, so they are not equal to it.
+func restrictConstraint(c Constraint, name string) (Constraint, bool) {
+	if c == "" {
+		return c, true
+	}
+	var atoms []constraintAtom
+	for _, atom := range c.atoms() {
+		if atom.Left == name || atom.Right == name {
+			if !atom.Inequality {
+				return "", false
+			}
+			continue
+		}
+		atoms = append(atoms, atom)
+	}
+	return newConstraint(atoms), true
+}
+
+// getCommConstraint returns the constraint of a communication between two
+// transitions of parallel components, and whether they may communicate: their
+// channels must be equal, or in symbolic semantics, constrained to be equal.
+func getCommConstraint(lconf Configuration, rconf Configuration) (Constraint, bool) {
+	var channels []constraintAtom
+	if lconf.Label.Symbol.Value != rconf.Label.Symbol.Value {
+		if !symbolicNames {
+			return "", false
+		}
+		channels = append(channels, constraintAtom{
+			Left:  lconf.Registers.GetName(lconf.Label.Symbol.Value),
+			Right: rconf.Registers.GetName(rconf.Label.Symbol.Value),
+		})
+	}
+	if lconf.Label.Constraint == "" && rconf.Label.Constraint == "" && channels == nil {
+		return "", true
+	}
+	return conjoinConstraint(lconf.Label.Constraint,
+		append(rconf.Label.Constraint.atoms(), channels...)...)
+}
+
+// transSymbolicMatch returns the transitions of a match under its constraint.
+func transSymbolicMatch(conf Configuration) []Configuration {
+	matchElem := conf.Process.(*ElemEquality)
+	atom := constraintAtom{
+		Left:       matchElem.NameL.Name,
+		Right:      matchElem.NameR.Name,
+		Inequality: matchElem.Inequality,
+	}
+	matchConf := conf
+	matchConf.Process = matchElem.Next
+
+	var confs []Configuration
+	for _, tconf := range trans(matchConf) {
+		constraint, ok := conjoinConstraint(tconf.Label.Constraint, atom)
+		if ok {
+			tconf.Label.Constraint = constraint
+			if explainTransitions {
+				tconf.Provenance = derive(RuleMatch, tconf.Provenance)
+			}
+			confs = append(confs, tconf)
+		}
+	}
+	return confs
+}
+
+// transSymbolic returns the transitions of a state with symbolic semantics,
+// with constraints over the register labels of the state.
+func transSymbolic(state Configuration) []Configuration {
+	confs := storePlaceholders(trans(state))
+	for i, conf := range confs {
+		if conf.Label.Constraint == "" {
+			continue
+		}
+		var atoms []constraintAtom
+		for _, atom := range conf.Label.Constraint.atoms() {
+			atoms = append(atoms, constraintAtom{
+				Left:       strconv.Itoa(state.Registers.GetLabel(atom.Left)),
+				Right:      strconv.Itoa(state.Registers.GetLabel(atom.Right)),
+				Inequality: atom.Inequality,
+			})
+		}
+		confs[i].Label.Constraint = newConstraint(atoms)
+	}
+	return confs
+}
+
+// holds returns whether a constraint over register labels holds when the
+// names at the labels have the given identities.
+func (c Constraint) holds(ids map[int]int) bool {
+	for _, atom := range c.atoms() {
+		left, _ := strconv.Atoi(atom.Left)
+		right, _ := strconv.Atoi(atom.Right)
+		if (ids[left] == ids[right]) == atom.Inequality {
+			return false
+		}
+	}
+	return true
+}
+
+// prettyPrint returns a constraint in brackets, with the given inequality
+// symbol and separator, or the empty string if the constraint is true.
+func (c Constraint) prettyPrint(neq string, sep string) string {
+	if c == "" {
+		return ""
+	}
+	var strs []string
+	for _, atom := range c.atoms() {
+		if atom.Inequality {
+			strs = append(strs, atom.Left+neq+atom.Right)
+		} else {
+			strs = append(strs, atom.Left+"="+atom.Right)
+		}
+	}
+	return "[" + strings.Join(strs, sep) + "]"
+}
+
+// checkSymbolicBisimilarity decides whether the roots of two symbolic LTSs
+// are strongly bisimilar, giving the names of a pair identities under which
+// the constraints are evaluated.
+func checkSymbolicBisimilarity(left Lts, right Lts) bisimResult {
+	c := &bisimChecker{
+		lts:     [2]Lts{left, right},
+		pairIds: make(map[string]int),
+	}
+	for side, lts := range c.lts {
+		c.moveTrns[side] = getTransitionsBySource(lts.Transitions)
+		c.matchTrns[side] = c.moveTrns[side]
+	}
+
+	c.addSymbolicPair(0, 0, getRootIds(left, right))
+	for id := 0; id < len(c.pairs); id++ {
+		c.moves = append(c.moves, c.getSymbolicMoves(id))
+	}
+
+	rank, witness := c.getRanks()
+	result := bisimResult{
+		Bisimilar: rank[0] == 0,
+		Bounded:   c.bounded,
+		Pairs:     len(c.pairs),
+	}
+	if !result.Bisimilar {
+		result.Trace = c.getTrace(rank, witness)
+	}
+	return result
+}
+
+// getRootIds gives the same identity to the register labels of the roots
+// holding the same original names.
+func getRootIds(left Lts, right Lts) [2]map[int]int {
+	nameIds := make(map[string]int)
+	var ids [2]map[int]int
+	for side, lts := range []Lts{left, right} {
+		ids[side] = make(map[int]int)
+		for label, name := range getRootNames(lts) {
+			if _, ok := nameIds[name]; !ok {
+				nameIds[name] = len(nameIds) + 1
+			}
+			ids[side][label] = nameIds[name]
+		}
+	}
+	return ids
+}
+
+// addSymbolicPair adds a pair with the identities of the names of both
+// registers, renumbered canonically, and returns its ID.
+func (c *bisimChecker) addSymbolicPair(left int, right int, ids [2]map[int]int) int {
+	renumbered := make(map[int]int)
+	var canonical [2]map[int]int
+	var strs [2][]string
+	for side, state := range []int{left, right} {
+		canonical[side] = make(map[int]int)
+		reg := c.lts[side].States[state].Registers
+		for _, label := range reg.Labels() {
+			id := ids[side][label]
+			if _, ok := renumbered[id]; !ok {
+				renumbered[id] = len(renumbered) + 1
+			}
+			canonical[side][label] = renumbered[id]
+			strs[side] = append(strs[side], strconv.Itoa(label)+":"+strconv.Itoa(renumbered[id]))
+		}
+	}
+
+	key := strconv.Itoa(left) + ";" + strconv.Itoa(right) + ";" +
+		strings.Join(strs[0], ",") + ";" + strings.Join(strs[1], ",")
+	if id, ok := c.pairIds[key]; ok {
+		return id
+	}
+	id := len(c.pairs)
+	c.pairIds[key] = id
+	c.pairs = append(c.pairs, bisimPair{
+		Left:  left,
+		Right: right,
+		Ids:   canonical,
+	})
+	return id
+}
+
+// getSymbolicMoves returns the challenges of both sides of a pair of symbolic
+// LTSs with their matches, adding the resulting pairs.
+func (c *bisimChecker) getSymbolicMoves(id int) []bisimMove {
+	pair := c.pairs[id]
+	if !c.lts[0].isExplored(pair.Left) || !c.lts[1].isExplored(pair.Right) {
+		c.bounded = true
+		return nil
+	}
+
+	// The identity of a name fresh to both sides.
+	var fresh int
+	var values []int
+	seen := make(map[int]bool)
+	for _, ids := range pair.Ids {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				values = append(values, id)
+			}
+			if id > fresh {
+				fresh = id
+			}
+		}
+	}
+	fresh++
+	sort.Ints(values)
+	values = append(values, fresh)
+
+	var moves []bisimMove
+	for side := 0; side < 2; side++ {
+		mover, other := pair.Left, pair.Right
+		if side == 1 {
+			mover, other = other, mover
+		}
+		ids, otherIds := pair.Ids[side], pair.Ids[1-side]
+
+		for _, trn := range c.moveTrns[side][mover] {
+			if !trn.Label.Constraint.holds(ids) {
+				continue
+			}
+			received := []int{0}
+			if trn.Label.Symbol2.Type == SymbolTypPlaceholder {
+				received = values
+			}
+			for _, value := range received {
+				move := bisimMove{
+					Side:       side,
+					Transition: trn,
+				}
+				for label, id := range otherIds {
+					if id == value && (move.OtherKnown == 0 || label < move.OtherKnown) {
+						move.OtherKnown = label
+					}
+				}
+				for _, otrn := range c.matchTrns[1-side][other] {
+					if !otrn.Label.Constraint.holds(otherIds) {
+						continue
+					}
+					newId, ok := matchSymbolicLabel(trn.Label, otrn.Label, ids, otherIds, value, fresh)
+					if !ok {
+						continue
+					}
+					dstIds := [2]map[int]int{
+						getSuccessorIds(ids, c.lts[side].States[trn.Destination], trn.Label, newId),
+						getSuccessorIds(otherIds, c.lts[1-side].States[otrn.Destination], otrn.Label, newId),
+					}
+					var pairId int
+					if side == 0 {
+						pairId = c.addSymbolicPair(trn.Destination, otrn.Destination, dstIds)
+					} else {
+						pairId = c.addSymbolicPair(otrn.Destination, trn.Destination,
+							[2]map[int]int{dstIds[1], dstIds[0]})
+					}
+					move.Matches = append(move.Matches, bisimMatch{
+						Transition: otrn,
+						Pair:       pairId,
+					})
+				}
+				moves = append(moves, move)
+			}
+		}
+	}
+	return moves
+}
+
+// matchSymbolicLabel returns whether the label of the other side matches the
+// label of the mover, which receives a value if it inputs a placeholder, and
+// the identity of the name received or created by both.
+func matchSymbolicLabel(label Label, otherLabel Label, ids map[int]int, otherIds map[int]int,
+	value int, fresh int) (int, bool) {
+	if label.Symbol.Type != otherLabel.Symbol.Type {
+		return 0, false
+	}
+	if label.Symbol.Type == SymbolTypTau {
+		return 0, true
+	}
+	if ids[label.Symbol.Value] != otherIds[otherLabel.Symbol.Value] {
+		return 0, false
+	}
+
+	i := label.Symbol2.Value
+	j := otherLabel.Symbol2.Value
+	switch label.Symbol2.Type {
+	case SymbolTypKnown:
+		value = ids[i]
+		fallthrough
+	case SymbolTypPlaceholder:
+		switch otherLabel.Symbol2.Type {
+		case SymbolTypKnown:
+			return value, otherIds[j] == value
+		case SymbolTypPlaceholder:
+			return value, true
+		}
+	case SymbolTypFreshOutput:
+		return fresh, otherLabel.Symbol2.Type == SymbolTypFreshOutput
+	}
+	return 0, false
+}
+
+// getSuccessorIds returns the identities of the names of the register of the
+// destination of a transition, in which the name received or created has the
+// given identity.
+func getSuccessorIds(ids map[int]int, dst Configuration, label Label, newId int) map[int]int {
+	dstIds := make(map[int]int)
+	for _, l := range dst.Registers.Labels() {
+		dstIds[l] = ids[l]
+	}
+	switch label.Symbol2.Type {
+	case SymbolTypPlaceholder, SymbolTypFreshOutput:
+		dstIds[label.Symbol2.Value] = newId
+	}
+	return dstIds
+}
@@ -0,0 +1,124 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSymbolicSemantics(t *testing.T) {
+	tests := map[string]struct {
+		input  []byte
+		labels []string
+	}{
+		"match": {
+			input:  []byte(`a(x).b(y).[x=y]c'<x>.0`),
+			labels: []string{"1 1?", "2 2?", "[1=2] 3'1 "},
+		},
+		"nested_matches": {
+			input:  []byte(`a(x).[x=b][x!=b]c'<x>.0`),
+			labels: []string{"1 1?"},
+		},
+		"placeholder_channel": {
+			input:  []byte(`a(x).(x'<c>.0 | b(y).0)`),
+			labels: []string{"1 1?", "2 2?", "1'3 ", "[1=2] t   ", "1'3 ", "2 1?"},
+		},
+		"restricted_name": {
+			input:  []byte(`a(x).$c.[x=c]b'<c>.0`),
+			labels: []string{"1 1?"},
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		symbolicNames = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			symbolicNames = true
+			lts, err := generateLts(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var labels []string
+			for _, trn := range lts.Transitions {
+				labels = append(labels, PrettyPrintLabel(trn.Label))
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("got labels %q, want %q", labels, test.labels)
+			}
+		})
+	}
+}
+
+func TestCheckSymbolicBisimilarity(t *testing.T) {
+	tests := map[string]struct {
+		spec      []byte
+		impl      []byte
+		bisimilar bool
+	}{
+		"identical": {
+			spec:      []byte(`a(x).x'<x>.0`),
+			impl:      []byte(`a(y).y'<y>.0`),
+			bisimilar: true,
+		},
+		"match_substituted": {
+			spec:      []byte(`a(x).[x=b]c'<x>.0`),
+			impl:      []byte(`a(x).[x=b]c'<b>.0`),
+			bisimilar: true,
+		},
+		"match_removed": {
+			spec: []byte(`a(x).[x=b]c'<x>.0`),
+			impl: []byte(`a(x).c'<x>.0`),
+		},
+		"mismatch_branch": {
+			spec:      []byte(`a(x).[x=b]c'<x>.0`),
+			impl:      []byte(`a(x).([x=b]c'<b>.0 + [x!=b]0)`),
+			bisimilar: true,
+		},
+		"name_known_to_impl": {
+			spec: []byte(`a(x).x'<x>.0`),
+			impl: []byte(`a(x).[x!=b]x'<x>.0`),
+		},
+		"fresh_output": {
+			spec: []byte(`$x.a'<x>.x(y).0`),
+			impl: []byte(`$x.a'<x>.a(y).0`),
+		},
+		"communication_on_received_channel": {
+			spec:      []byte(`a(x).(x'<c>.0 | b(y).0)`),
+			impl:      []byte(`a(x).(b(y).0 | x'<c>.0)`),
+			bisimilar: true,
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+	defer func() {
+		symbolicNames = false
+	}()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			symbolicNames = true
+			var ltss [2]Lts
+			for i, input := range [][]byte{test.spec, test.impl} {
+				lts, err := generateLts(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ltss[i] = lts
+			}
+			result := checkSymbolicBisimilarity(ltss[0], ltss[1])
+			if result.Bisimilar != test.bisimilar {
+				t.Errorf("got symbolic bisimilar %t, want %t", result.Bisimilar, test.bisimilar)
+			}
+
+			// The early semantics agrees.
+			symbolicNames = false
+			spec, _ := generateLts(test.spec)
+			impl, _ := generateLts(test.impl)
+			if checkBisimilarity(spec, impl, false).Bisimilar != test.bisimilar {
+				t.Errorf("got early bisimilar %t, want %t", !test.bisimilar, test.bisimilar)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package pifra
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+)
+
+// symmetryReduction identifies states equal up to a permutation of the labels
+// of their non-marked registers. Each orbit is represented by its first state.
+var symmetryReduction bool
+
+// maxSymmetryPermutations bounds the register permutations tried when
+// canonicalising a state, beyond which labels are ranked by first occurrence.
+var maxSymmetryPermutations = 6
+
+// maxSymmetryRankRounds bounds the refinement rounds of the ranking.
+var maxSymmetryRankRounds = 4
+
+var freeNameRegexp = regexp.MustCompile(`#[0-9]+`)
+var boundNameRegexp = regexp.MustCompile(`&[0-9]+`)
+
+// getSymmetricKey returns the key of a configuration up to permutation of its
+// non-marked register labels, and the permutation from the register labels
+// of the configuration to the canonical labels of the key.
+func getSymmetricKey(conf Configuration) (string, map[int]int) {
+	var markedLabels []int
+	var labels []int
+	for _, label := range conf.Registers.Labels() {
+		if strings.HasPrefix(conf.Registers.GetName(label), "_") {
+			markedLabels = append(markedLabels, label)
+		} else {
+			labels = append(labels, label)
+		}
+	}
+
+	// Group the labels by the signature of their names. A permutation may only
+	// map a label to a label in the same group.
+	_, comps := getComponents(conf.Process)
+	var compStrs []string
+	for _, comp := range comps {
+		compStrs = append(compStrs, boundNameRegexp.ReplaceAllString(PrettyPrintAst(comp), "&"))
+	}
+	groups := make(map[string][]int)
+	for _, label := range labels {
+		sig := getNameSignature(conf.Registers.GetName(label), compStrs)
+		groups[sig] = append(groups[sig], label)
+	}
+	var sigs []string
+	for sig := range groups {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	// Canonical labels are assigned to groups in signature order.
+	numPerms := 1
+	for _, sig := range sigs {
+		for i := 2; i <= len(groups[sig]) && numPerms <= maxSymmetryPermutations; i++ {
+			numPerms = numPerms * i
+		}
+	}
+	var groupPerms [][][]int
+	if numPerms <= maxSymmetryPermutations {
+		for _, sig := range sigs {
+			groupPerms = append(groupPerms, permutations(groups[sig]))
+		}
+	}
+
+	markedStr := "{"
+	for _, label := range markedLabels {
+		markedStr = markedStr + "(" + strconv.Itoa(label) + "," + conf.Registers.GetName(label) + ")"
+	}
+	markedStr = markedStr + "}" + strconv.Itoa(len(labels))
+
+	if numPerms > maxSymmetryPermutations {
+		perm := rankNames(conf.Registers, labels, compStrs)
+		return markedStr + getLabelForm(conf, perm), perm
+	}
+
+	var minKey string
+	var minPerm map[int]int
+	var search func(group int, perm map[int]int, next int)
+	search = func(group int, perm map[int]int, next int) {
+		if group == len(groupPerms) {
+			key := markedStr + getLabelForm(conf, perm)
+			if minPerm == nil || key < minKey {
+				minKey = key
+				minPerm = make(map[int]int)
+				for k, v := range perm {
+					minPerm[k] = v
+				}
+			}
+			return
+		}
+		for _, order := range groupPerms[group] {
+			for i, label := range order {
+				perm[label] = next + i + 1
+			}
+			search(group+1, perm, next+len(order))
+		}
+	}
+	search(0, make(map[int]int), 0)
+
+	return minKey, minPerm
+}
+
+// rankNames ranks the non-marked register labels by the first occurrence of
+// their names in the parallel components, sorted by their text with ranked
+// names replaced by their ranks. The ranking is refined until it is stable.
+func rankNames(reg Registers, labels []int, compStrs []string) map[int]int {
+	labelOf := make(map[string]int)
+	for _, label := range labels {
+		labelOf[reg.GetName(label)] = label
+	}
+
+	perm := make(map[int]int)
+	for round := 0; round < maxSymmetryRankRounds; round++ {
+		strs := make([]string, len(compStrs))
+		for i, compStr := range compStrs {
+			strs[i] = freeNameRegexp.ReplaceAllStringFunc(compStr, func(fn string) string {
+				if rank, ok := perm[labelOf[fn]]; ok {
+					return "@" + strconv.Itoa(rank)
+				}
+				return "#"
+			})
+		}
+		order := make([]int, len(compStrs))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return strs[order[i]] < strs[order[j]]
+		})
+
+		newPerm := make(map[int]int)
+		for _, i := range order {
+			for _, fn := range freeNameRegexp.FindAllString(compStrs[i], -1) {
+				if label, ok := labelOf[fn]; ok {
+					if _, ok := newPerm[label]; !ok {
+						newPerm[label] = len(newPerm) + 1
+					}
+				}
+			}
+		}
+		// Names not occurring in the process.
+		for _, label := range labels {
+			if _, ok := newPerm[label]; !ok {
+				newPerm[label] = len(newPerm) + 1
+			}
+		}
+
+		stable := len(perm) == len(newPerm)
+		for label, rank := range newPerm {
+			if perm[label] != rank {
+				stable = false
+			}
+		}
+		perm = newPerm
+		if stable {
+			break
+		}
+	}
+	return perm
+}
+
+// getNameSignature returns a description of the parallel components in which
+// a name occurs, invariant under renaming of free and bound names.
+func getNameSignature(name string, compStrs []string) string {
+	var sig []string
+	for _, compStr := range compStrs {
+		occurs := false
+		abstract := freeNameRegexp.ReplaceAllStringFunc(compStr, func(fn string) string {
+			if fn == name {
+				occurs = true
+				return "@"
+			}
+			return "#"
+		})
+		if occurs {
+			sig = append(sig, abstract)
+		}
+	}
+	sort.Strings(sig)
+	return strings.Join(sig, "|")
+}
+
+// getLabelForm returns the process of a configuration with every non-marked
+// free name replaced by its canonical register label.
+func getLabelForm(conf Configuration, perm map[int]int) string {
+	proc := deepcopy.Copy(conf.Process).(Element)
+	for label, permLabel := range perm {
+		subName(proc, Name{
+			Name: conf.Registers.GetName(label),
+		}, Name{
+			Name: "@" + strconv.Itoa(permLabel),
+		})
+	}
+	c := Configuration{
+		Process: proc,
+		Registers: Registers{
+			Registers: make(map[int]string),
+		},
+	}
+	sortSumPar(c.Process)
+	normaliseBoundNames(c)
+	sortSumPar(c.Process)
+	sortRes(c.Process)
+	return PrettyPrintAst(c.Process)
+}
+
+// permutations returns all orderings of the labels.
+func permutations(labels []int) [][]int {
+	if len(labels) <= 1 {
+		return [][]int{append([]int{}, labels...)}
+	}
+	var perms [][]int
+	for i := range labels {
+		rest := append(append([]int{}, labels[:i]...), labels[i+1:]...)
+		for _, perm := range permutations(rest) {
+			perms = append(perms, append([]int{labels[i]}, perm...))
+		}
+	}
+	return perms
+}
@@ -0,0 +1,79 @@
+package pifra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSymmetryReduction(t *testing.T) {
+	tests := map[string]struct {
+		input           []byte
+		states          int
+		orbitsCollapsed int
+	}{
+		"replicas": {
+			input: []byte(`
+P(a) = a(x).x'<x>.0
+P(a) | P(b)
+`),
+			states:          8,
+			orbitsCollapsed: 4,
+		},
+		"replicated_server": {
+			input: []byte(`
+S(a) = a(x).$y.x'<y>.0
+S(a) | S(b) | S(c)
+`),
+			states:          25,
+			orbitsCollapsed: 18,
+		},
+		"no_symmetry": {
+			input: []byte(`
+a(x).b'<x>.0
+`),
+			states:          4,
+			orbitsCollapsed: 0,
+		},
+	}
+	maxStatesExplored = 1000
+	registerSize = 1073741824
+	defer func() {
+		symmetryReduction = false
+	}()
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			symmetryReduction = false
+			lts, err := generateLts(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			symmetryReduction = true
+			reducedLts, err := generateLts(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(reducedLts.States) != tc.states {
+				t.Errorf("states: expected %d, got %d", tc.states, len(reducedLts.States))
+			}
+			if reducedLts.OrbitsCollapsed != tc.orbitsCollapsed {
+				t.Errorf("orbits collapsed: expected %d, got %d", tc.orbitsCollapsed, reducedLts.OrbitsCollapsed)
+			}
+
+			// Every state has a representative, and every representative is a state.
+			orbits := make(map[string]bool)
+			for _, state := range lts.States {
+				key, _ := getSymmetricKey(state)
+				orbits[key] = true
+			}
+			reducedOrbits := make(map[string]bool)
+			for _, state := range reducedLts.States {
+				key, _ := getSymmetricKey(state)
+				reducedOrbits[key] = true
+			}
+			if !reflect.DeepEqual(orbits, reducedOrbits) {
+				t.Errorf("orbits differ: expected %v, got %v", orbits, reducedOrbits)
+			}
+		})
+	}
+}
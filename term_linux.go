@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package pifra
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal of a file descriptor into raw mode, so that the
+// line editor reads each key as it is pressed, and returns a function which
+// restores its mode. It fails if the file descriptor is not a terminal.
+func makeRaw(fd int) (func(), error) {
+	var termios syscall.Termios
+	if err := ioctlTermios(fd, syscall.TCGETS, &termios); err != nil {
+		return nil, err
+	}
+	saved := termios
+	termios.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.INLCR | syscall.IGNCR
+	termios.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(fd, syscall.TCSETS, &termios); err != nil {
+		return nil, err
+	}
+	return func() {
+		ioctlTermios(fd, syscall.TCSETS, &saved)
+	}, nil
+}
+
+func ioctlTermios(fd int, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request,
+		uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
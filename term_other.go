@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package pifra
+
+import (
+	"errors"
+)
+
+// makeRaw is not supported on this platform, so lines are read as they are.
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
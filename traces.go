@@ -0,0 +1,411 @@
+package pifra
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// traceElem is a state of an LTS with the identities of the names in its
+// register, shared with the other states of a traceMacro.
+type traceElem struct {
+	State int
+	Names map[int]int
+}
+
+// traceMacro is a state of the subset construction: a state of the
+// implementation and the set of states of the specification reachable by the
+// same trace, with the names held by each.
+type traceMacro struct {
+	Impl  traceElem
+	Specs []traceElem
+}
+
+// traceStep is a transition of the implementation in an offending trace. A
+// fresh input may receive a name known to the specification but not to the
+// implementation.
+type traceStep struct {
+	Transition Transition
+	SpecName   bool
+}
+
+type traceResult struct {
+	Included bool
+	// Whether a state which was not explored was reached, in which case its
+	// traces are assumed to be included.
+	Bounded bool
+	Macros  int
+	Trace   []traceStep
+}
+
+// traceChecker decides trace inclusion by exploring the subset construction
+// of the specification on the fly, breadth first, so the first trace which the
+// specification cannot follow is a shortest one.
+type traceChecker struct {
+	impl Lts
+	spec Lts
+	weak bool
+
+	implTrns map[int][]Transition
+	specTrns map[int][]Transition
+}
+
+// checkTraceInclusion decides whether every trace of the implementation is a
+// trace of the specification, comparing names by identity rather than label.
+func checkTraceInclusion(impl Lts, spec Lts, weak bool) traceResult {
+	c := &traceChecker{
+		impl:     impl,
+		spec:     spec,
+		weak:     weak,
+		implTrns: getTransitionsBySource(impl.Transitions),
+		specTrns: getTransitionsBySource(spec.Transitions),
+	}
+	if weak {
+		c.specTrns = getTransitionsBySource(saturateTau(len(spec.States), spec.Transitions))
+	}
+
+	// Identify the names of the roots by their original names.
+	ids := make(map[string]int)
+	getNames := func(lts Lts) map[int]int {
+		names := make(map[int]int)
+		for label, name := range getRootNames(lts) {
+			if _, ok := ids[name]; !ok {
+				ids[name] = len(ids) + 1
+			}
+			names[label] = ids[name]
+		}
+		return names
+	}
+	root := traceMacro{
+		Impl: traceElem{
+			State: 0,
+			Names: getNames(impl),
+		},
+		Specs: []traceElem{{
+			State: 0,
+			Names: getNames(spec),
+		}},
+	}
+	if weak {
+		root.Specs = c.getTauClosure(root.Specs)
+	}
+
+	type parent struct {
+		Macro int
+		Step  traceStep
+	}
+	var macros []traceMacro
+	var parents []parent
+	visited := make(map[string]bool)
+	add := func(macro traceMacro, p parent) {
+		key := getTraceMacroKey(macro)
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		macros = append(macros, macro)
+		parents = append(parents, p)
+	}
+	add(root, parent{Macro: -1})
+
+	result := traceResult{
+		Included: true,
+	}
+	for id := 0; id < len(macros); id++ {
+		macro := macros[id]
+		if !c.isExplored(macro) {
+			result.Bounded = true
+			continue
+		}
+		for _, trn := range c.implTrns[macro.Impl.State] {
+			for _, succ := range c.getSuccessors(macro, trn) {
+				step := traceStep{
+					Transition: trn,
+					SpecName:   succ.SpecName,
+				}
+				if len(succ.Macro.Specs) == 0 {
+					result.Included = false
+					result.Macros = len(macros)
+					result.Trace = []traceStep{step}
+					for p := id; parents[p].Macro != -1; p = parents[p].Macro {
+						result.Trace = append([]traceStep{parents[p].Step}, result.Trace...)
+					}
+					return result
+				}
+				add(succ.Macro, parent{
+					Macro: id,
+					Step:  step,
+				})
+			}
+		}
+	}
+	result.Macros = len(macros)
+	return result
+}
+
+// isExplored returns whether the transitions of every state of a macro state
+// were generated.
+func (c *traceChecker) isExplored(macro traceMacro) bool {
+	if !c.impl.isExplored(macro.Impl.State) {
+		return false
+	}
+	for _, spec := range macro.Specs {
+		if !c.spec.isExplored(spec.State) {
+			return false
+		}
+	}
+	return true
+}
+
+// getTauClosure returns the states reachable by τ-transitions, which are
+// included in the saturated transitions of the specification.
+func (c *traceChecker) getTauClosure(specs []traceElem) []traceElem {
+	var closure []traceElem
+	for _, spec := range specs {
+		for _, trn := range c.specTrns[spec.State] {
+			if trn.Label.Symbol.Type == SymbolTypTau {
+				closure = append(closure, c.newElem(c.spec, trn.Destination, spec.Names))
+			}
+		}
+	}
+	return dedupTraceElems(closure)
+}
+
+type traceSuccessor struct {
+	Macro    traceMacro
+	SpecName bool
+}
+
+// getSuccessors returns the macro states reached by a transition of the
+// implementation, one for each name a fresh input may receive.
+func (c *traceChecker) getSuccessors(macro traceMacro, trn Transition) []traceSuccessor {
+	label := trn.Label
+	if label.Symbol.Type == SymbolTypTau && c.weak {
+		return []traceSuccessor{{
+			Macro: traceMacro{
+				Impl:  c.newElem(c.impl, trn.Destination, macro.Impl.Names),
+				Specs: macro.Specs,
+			},
+		}}
+	}
+
+	newId := 1
+	for _, elem := range append([]traceElem{macro.Impl}, macro.Specs...) {
+		for _, id := range elem.Names {
+			if id >= newId {
+				newId = id + 1
+			}
+		}
+	}
+
+	// The identity of the name sent or received, if any.
+	var names []int
+	var specNames []bool
+	switch label.Symbol2.Type {
+	case SymbolTypKnown:
+		names = []int{macro.Impl.Names[label.Symbol2.Value]}
+		specNames = []bool{false}
+	case SymbolTypFreshOutput:
+		names = []int{newId}
+		specNames = []bool{false}
+	case SymbolTypFreshInput:
+		names = []int{newId}
+		specNames = []bool{false}
+		implIds := make(map[int]bool)
+		for _, id := range macro.Impl.Names {
+			implIds[id] = true
+		}
+		specIds := make(map[int]bool)
+		for _, spec := range macro.Specs {
+			for _, id := range spec.Names {
+				if !implIds[id] {
+					specIds[id] = true
+				}
+			}
+		}
+		var ids []int
+		for id := range specIds {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			names = append(names, id)
+			specNames = append(specNames, true)
+		}
+	default:
+		names = []int{0}
+		specNames = []bool{false}
+	}
+
+	var succs []traceSuccessor
+	for i, name := range names {
+		implNames := copyNames(macro.Impl.Names)
+		if label.Symbol2.Type == SymbolTypFreshInput || label.Symbol2.Type == SymbolTypFreshOutput {
+			implNames[label.Symbol2.Value] = name
+		}
+		var specs []traceElem
+		for _, spec := range macro.Specs {
+			for _, strn := range c.specTrns[spec.State] {
+				ext, ok := matchTraceLabel(label, macro.Impl.Names, strn.Label, spec.Names, name)
+				if ok {
+					specs = append(specs, c.newElem(c.spec, strn.Destination, ext))
+				}
+			}
+		}
+		succs = append(succs, traceSuccessor{
+			Macro: traceMacro{
+				Impl:  c.newElem(c.impl, trn.Destination, implNames),
+				Specs: dedupTraceElems(specs),
+			},
+			SpecName: specNames[i],
+		})
+	}
+	return succs
+}
+
+// matchTraceLabel returns whether a label of the specification denotes the
+// same action as a label of the implementation, and the names of the
+// specification state extended with the name received or created.
+func matchTraceLabel(label Label, names map[int]int, specLabel Label, specNames map[int]int, name int) (map[int]int, bool) {
+	if label.Symbol.Type != specLabel.Symbol.Type {
+		return nil, false
+	}
+	if label.Symbol.Type == SymbolTypTau {
+		return specNames, true
+	}
+	if id, ok := specNames[specLabel.Symbol.Value]; !ok || id != names[label.Symbol.Value] {
+		return nil, false
+	}
+
+	held := false
+	for _, id := range specNames {
+		if id == name {
+			held = true
+		}
+	}
+	switch specLabel.Symbol2.Type {
+	case SymbolTypKnown:
+		return specNames, specNames[specLabel.Symbol2.Value] == name
+	case SymbolTypFreshInput:
+		if held {
+			return nil, false
+		}
+	case SymbolTypFreshOutput:
+		if label.Symbol2.Type != SymbolTypFreshOutput {
+			return nil, false
+		}
+	}
+	ext := copyNames(specNames)
+	ext[specLabel.Symbol2.Value] = name
+	return ext, true
+}
+
+func copyNames(names map[int]int) map[int]int {
+	cp := make(map[int]int)
+	for label, id := range names {
+		cp[label] = id
+	}
+	return cp
+}
+
+// newElem returns a state with the names restricted to its register.
+func (c *traceChecker) newElem(lts Lts, state int, names map[int]int) traceElem {
+	reg := lts.States[state].Registers.Registers
+	restricted := make(map[int]int)
+	for label, id := range names {
+		if _, ok := reg[label]; ok {
+			restricted[label] = id
+		}
+	}
+	return traceElem{
+		State: state,
+		Names: restricted,
+	}
+}
+
+func dedupTraceElems(elems []traceElem) []traceElem {
+	var dedup []traceElem
+	seen := make(map[string]bool)
+	for _, elem := range elems {
+		key := getTraceElemKey(elem, nil)
+		if !seen[key] {
+			seen[key] = true
+			dedup = append(dedup, elem)
+		}
+	}
+	return dedup
+}
+
+// getTraceElemKey returns the key of a state with its names renumbered, or
+// with their identities if renumber is nil. Names not yet renumbered are
+// written ?.
+func getTraceElemKey(elem traceElem, renumber map[int]int) string {
+	var labels []int
+	for label := range elem.Names {
+		labels = append(labels, label)
+	}
+	sort.Ints(labels)
+	var strs []string
+	for _, label := range labels {
+		id := elem.Names[label]
+		idStr := strconv.Itoa(id)
+		if renumber != nil {
+			idStr = "?"
+			if rid, ok := renumber[id]; ok {
+				idStr = strconv.Itoa(rid)
+			}
+		}
+		strs = append(strs, strconv.Itoa(label)+":"+idStr)
+	}
+	return strconv.Itoa(elem.State) + "{" + strings.Join(strs, ",") + "}"
+}
+
+// getTraceMacroKey returns the key of a macro state, with the identities of
+// names renumbered by their first occurrence, so that macro states equal up to
+// renaming have the same key.
+func getTraceMacroKey(macro traceMacro) string {
+	renumber := make(map[int]int)
+	addNames := func(elem traceElem) {
+		var labels []int
+		for label := range elem.Names {
+			labels = append(labels, label)
+		}
+		sort.Ints(labels)
+		for _, label := range labels {
+			if _, ok := renumber[elem.Names[label]]; !ok {
+				renumber[elem.Names[label]] = len(renumber) + 1
+			}
+		}
+	}
+
+	addNames(macro.Impl)
+	specs := append([]traceElem{}, macro.Specs...)
+	sort.SliceStable(specs, func(i, j int) bool {
+		return getTraceElemKey(specs[i], renumber) < getTraceElemKey(specs[j], renumber)
+	})
+	for _, spec := range specs {
+		addNames(spec)
+	}
+
+	var strs []string
+	for _, spec := range specs {
+		strs = append(strs, getTraceElemKey(spec, renumber))
+	}
+	sort.Strings(strs)
+	return getTraceElemKey(macro.Impl, renumber) + "|" + strings.Join(strs, "|")
+}
+
+// prettyPrintTrace returns a trace of an LTS with a line per transition.
+func prettyPrintTrace(trace []traceStep, name string) string {
+	var lines []string
+	for _, step := range trace {
+		trn := step.Transition
+		line := name + "  s" + strconv.Itoa(trn.Source) + "  " + PrettyPrintLabel(trn.Label) +
+			"  s" + strconv.Itoa(trn.Destination)
+		if step.SpecName {
+			line = line + "  receiving a name known only to the specification"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
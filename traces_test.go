@@ -0,0 +1,92 @@
+package pifra
+
+import (
+	"testing"
+)
+
+func TestCheckTraceInclusion(t *testing.T) {
+	tests := map[string]struct {
+		impl     []byte
+		spec     []byte
+		strong   int
+		weak     int
+		converse int
+	}{
+		"identical": {
+			impl: []byte(`a(x).x'<x>.0`),
+			spec: []byte(`a(y).y'<y>.0`),
+		},
+		"branching": {
+			impl: []byte(`a'<a>.b'<b>.0 + a'<a>.c'<c>.0`),
+			spec: []byte(`a'<a>.(b'<b>.0 + c'<c>.0)`),
+		},
+		"fewer_traces": {
+			impl:     []byte(`a'<a>.0`),
+			spec:     []byte(`a'<a>.b'<b>.0`),
+			converse: 2,
+		},
+		"fresh_output_channel": {
+			impl:     []byte(`a(x).a'<x>.0`),
+			spec:     []byte(`a(x).x'<x>.0`),
+			strong:   2,
+			weak:     2,
+			converse: 2,
+		},
+		"internal_step": {
+			impl:     []byte(`a(y).$c.(c'<c>.0 | c(z).y'<y>.0)`),
+			spec:     []byte(`a(x).x'<x>.0`),
+			strong:   2,
+			converse: 0,
+		},
+		"name_known_to_spec": {
+			impl:     []byte(`a(x).x'<x>.0`),
+			spec:     []byte(`a(x).[x!=b]x'<x>.0`),
+			strong:   2,
+			weak:     2,
+			converse: 0,
+		},
+		"fresh_output": {
+			impl:     []byte(`$x.a'<x>.x(y).0`),
+			spec:     []byte(`$x.a'<x>.(a(y).0 + x(y).0)`),
+			converse: 2,
+		},
+		"fresh_output_not_fresh": {
+			impl:     []byte(`$x.a'<x>.0`),
+			spec:     []byte(`a'<b>.0`),
+			strong:   1,
+			weak:     1,
+			converse: 1,
+		},
+	}
+	maxStatesExplored = 100
+	registerSize = 1073741824
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			impl, err := generateLts(test.impl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := generateLts(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check := func(desc string, impl Lts, spec Lts, weak bool, want int) {
+				result := checkTraceInclusion(impl, spec, weak)
+				if result.Included != (want == 0) {
+					t.Errorf("%s: got included %t, want %t", desc, result.Included, want == 0)
+				}
+				if result.Bounded {
+					t.Errorf("%s: unexpected unexplored state", desc)
+				}
+				if len(result.Trace) != want {
+					t.Errorf("%s: got trace of length %d, want %d\n%s", desc, len(result.Trace), want,
+						prettyPrintTrace(result.Trace, "impl"))
+				}
+			}
+			check("strong", impl, spec, false, test.strong)
+			check("weak", impl, spec, true, test.weak)
+			check("converse", spec, impl, true, test.converse)
+		})
+	}
+}
@@ -14,6 +14,8 @@ type Configuration struct {
 	Process   Element
 	Registers Registers
 	Label     Label
+	// The derivation of the transition to the configuration.
+	Provenance Provenance
 }
 
 type SymbolType int
@@ -25,6 +27,11 @@ const (
 	SymbolTypFreshInput
 	SymbolTypFreshOutput
 	SymbolTypKnown
+	// SymbolTypPlaceholder is the placeholder stored by a late input.
+	SymbolTypPlaceholder
+	// SymbolTypInstance is the label of a placeholder instantiated in late
+	// semantics.
+	SymbolTypInstance
 )
 
 type Symbol struct {
@@ -35,11 +42,15 @@ type Symbol struct {
 type Label struct {
 	Symbol  Symbol
 	Symbol2 Symbol
+	// The constraint of the transition in symbolic semantics.
+	Constraint Constraint
 }
 
 type Registers struct {
 	Size      int
 	Registers map[int]string
+	// The names known to the environment, if it is restricted.
+	Known map[string]bool
 }
 
 // UpdateMax adds a free name to the register at the register size + 1 and
@@ -265,6 +276,13 @@ func trans(conf Configuration) []Configuration {
 				Type:  SymbolTypKnown,
 				Value: label,
 			}
+			if explainTransitions {
+				inp2aConf.Provenance = Provenance{
+					Rule:      RuleInp2a,
+					Position:  inp2aElem.Position,
+					Component: inp2aElem.Component,
+				}
+			}
 			inp2aConf.Process = inp2aElem.Next
 			confs = append(confs, inp2aConf)
 		}
@@ -284,6 +302,13 @@ func trans(conf Configuration) []Configuration {
 			Type:  SymbolTypFreshInput,
 			Value: inp2bConf.Registers.UpdateMin(name, freshNamesP),
 		}
+		if explainTransitions {
+			inp2bConf.Provenance = Provenance{
+				Rule:      RuleInp2b,
+				Position:  inp2bElem.Position,
+				Component: inp2bElem.Component,
+			}
+		}
 		inp2bConf.Process = inp2bElem.Next
 
 		return append(confs, inp2bConf)
@@ -312,12 +337,23 @@ func trans(conf Configuration) []Configuration {
 			Type:  SymbolTypKnown,
 			Value: label,
 		}
+		if explainTransitions {
+			out2Conf.Provenance = Provenance{
+				Rule:      RuleOut,
+				Position:  out2Elem.Position,
+				Component: out2Elem.Component,
+			}
+		}
 		out2Conf.Process = out2Elem.Next
 		confs = append(confs, out2Conf)
 		return confs
 
 	// MATCH
 	case ElemTypMatch:
+		if symbolicNames {
+			return transSymbolicMatch(conf)
+		}
+
 		var confs []Configuration
 
 		matchElem := conf.Process.(*ElemEquality)
@@ -330,6 +366,11 @@ func trans(conf Configuration) []Configuration {
 			matchConf.Process = matchElem.Next
 			// o ¦- P -t-> o ¦- P^'
 			tconfs := trans(matchConf)
+			if explainTransitions {
+				for i := range tconfs {
+					tconfs[i].Provenance = derive(RuleMatch, tconfs[i].Provenance)
+				}
+			}
 			// o ¦- P^'
 			confs = append(confs, tconfs...)
 		}
@@ -355,6 +396,13 @@ func trans(conf Configuration) []Configuration {
 		tconfs := trans(resConf)
 		// (o'+a) ¦- P^
 		for _, conf := range tconfs {
+			// A name received before the restriction is not the restricted name.
+			constraint, ok := restrictConstraint(conf.Label.Constraint, resName)
+			if !ok {
+				continue
+			}
+			conf.Label.Constraint = constraint
+
 			// OPEN
 			if conf.Label.Symbol.Value != resLabel && conf.Label.Symbol2.Value != resLabel {
 				// $a.P^'
@@ -374,6 +422,9 @@ func trans(conf Configuration) []Configuration {
 					Type: Bound,
 				})
 
+				if explainTransitions {
+					conf.Provenance = derive(RuleRes, conf.Provenance)
+				}
 				confs = append(confs, conf)
 			}
 
@@ -403,6 +454,9 @@ func trans(conf Configuration) []Configuration {
 				})
 
 				// o |- P'
+				if explainTransitions {
+					conf.Provenance = derive(RuleOpen, conf.Provenance)
+				}
 				confs = append(confs, conf)
 			}
 		}
@@ -433,6 +487,9 @@ func trans(conf Configuration) []Configuration {
 
 		procConf.Process = proc
 		doAlphaConversion(proc)
+		if explainTransitions {
+			tagComponent(proc, generateComponent(processName))
+		}
 
 		// Create visited processes set.
 		if recVisitedProcs == nil {
@@ -445,6 +502,11 @@ func trans(conf Configuration) []Configuration {
 		recVisitedProcs[processName] = true
 		tconfs := trans(procConf)
 		recVisitedProcs = nil
+		if explainTransitions {
+			for i := range tconfs {
+				tconfs[i].Provenance = derive(RuleRec, tconfs[i].Provenance)
+			}
+		}
 
 		return tconfs
 
@@ -457,6 +519,11 @@ func trans(conf Configuration) []Configuration {
 		sumElem := sumConf.Process.(*ElemSum)
 		sumConf.Process = sumElem.ProcessL
 		lconfs := trans(sumConf)
+		if explainTransitions {
+			for i := range lconfs {
+				lconfs[i].Provenance = derive(RuleSum, lconfs[i].Provenance)
+			}
+		}
 		confs = append(confs, lconfs...)
 
 		// SUM_R
@@ -464,6 +531,11 @@ func trans(conf Configuration) []Configuration {
 		sumElem = sumConf.Process.(*ElemSum)
 		sumConf.Process = sumElem.ProcessR
 		rconfs := trans(sumConf)
+		if explainTransitions {
+			for i := range rconfs {
+				rconfs[i].Provenance = derive(RuleSum, rconfs[i].Provenance)
+			}
+		}
 		confs = append(confs, rconfs...)
 
 		return confs
@@ -479,7 +551,7 @@ func trans(conf Configuration) []Configuration {
 		parConf := deepcopy.Copy(conf).(Configuration)
 		parElem := parConf.Process.(*ElemParallel)
 		parConf.Process = parElem.ProcessL
-		tconfs := trans(parConf)
+		tconfs := transComponent(parConf)
 
 		// PAR2_L
 		for _, conf := range tconfs {
@@ -505,6 +577,9 @@ func trans(conf Configuration) []Configuration {
 			}
 			// Insert P' to P' | Q.
 			parConf.Process.(*ElemParallel).ProcessL = conf.Process
+			if explainTransitions {
+				parConf.Provenance = derive(RulePar, conf.Provenance)
+			}
 
 			lconfs = append(lconfs, parConf)
 		}
@@ -513,7 +588,7 @@ func trans(conf Configuration) []Configuration {
 		parConf = deepcopy.Copy(conf).(Configuration)
 		parElem = parConf.Process.(*ElemParallel)
 		parConf.Process = parElem.ProcessR
-		tconfs = trans(parConf)
+		tconfs = transComponent(parConf)
 
 		// PAR2_R
 		for _, conf := range tconfs {
@@ -538,6 +613,9 @@ func trans(conf Configuration) []Configuration {
 			}
 			// Insert Q' to P | Q'.
 			parConf.Process.(*ElemParallel).ProcessR = conf.Process
+			if explainTransitions {
+				parConf.Provenance = derive(RulePar, conf.Provenance)
+			}
 
 			rconfs = append(rconfs, parConf)
 		}
@@ -551,8 +629,11 @@ func trans(conf Configuration) []Configuration {
 					lconf.Label.Symbol2.Type == SymbolTypKnown &&
 					rconf.Label.Symbol.Type == SymbolTypInput &&
 					rconf.Label.Symbol2.Type == SymbolTypKnown &&
-					lconf.Label.Symbol.Value == rconf.Label.Symbol.Value &&
 					lconf.Label.Symbol2.Value == rconf.Label.Symbol2.Value {
+					constraint, ok := getCommConstraint(lconf, rconf)
+					if !ok {
+						continue
+					}
 					lproc := deepcopy.Copy(lconf.Process).(Element).(*ElemParallel).ProcessL
 					rproc := deepcopy.Copy(rconf.Process).(Element).(*ElemParallel).ProcessR
 					comm := deepcopy.Copy(basePar).(Configuration)
@@ -564,6 +645,12 @@ func trans(conf Configuration) []Configuration {
 						Symbol: Symbol{
 							Type: SymbolTypTau,
 						},
+						Constraint: constraint,
+					}
+					// The premises are the transitions of the components.
+					if explainTransitions {
+						comm.Provenance = derive(RuleComm, lconf.Provenance.Premises[0],
+							rconf.Provenance.Premises[0])
 					}
 					confs = append(confs, comm)
 				}
@@ -577,8 +664,11 @@ func trans(conf Configuration) []Configuration {
 					lconf.Label.Symbol2.Type == SymbolTypKnown &&
 					rconf.Label.Symbol.Type == SymbolTypOutput &&
 					rconf.Label.Symbol2.Type == SymbolTypKnown &&
-					lconf.Label.Symbol.Value == rconf.Label.Symbol.Value &&
 					lconf.Label.Symbol2.Value == rconf.Label.Symbol2.Value {
+					constraint, ok := getCommConstraint(lconf, rconf)
+					if !ok {
+						continue
+					}
 					lproc := deepcopy.Copy(lconf.Process).(Element).(*ElemParallel).ProcessL
 					rproc := deepcopy.Copy(rconf.Process).(Element).(*ElemParallel).ProcessR
 					comm := deepcopy.Copy(basePar).(Configuration)
@@ -590,6 +680,12 @@ func trans(conf Configuration) []Configuration {
 						Symbol: Symbol{
 							Type: SymbolTypTau,
 						},
+						Constraint: constraint,
+					}
+					// The premises are the transitions of the components.
+					if explainTransitions {
+						comm.Provenance = derive(RuleComm, lconf.Provenance.Premises[0],
+							rconf.Provenance.Premises[0])
 					}
 					confs = append(confs, comm)
 				}
@@ -604,7 +700,7 @@ func trans(conf Configuration) []Configuration {
 		// (#+o) ¦- P
 		clconf.Process = parElem.ProcessL
 		// -t-> (b+o) ¦- P'
-		clconfs := trans(clconf)
+		clconfs := transComponent(clconf)
 
 		crconf := deepcopy.Copy(conf).(Configuration)
 		// (#+o)
@@ -613,7 +709,7 @@ func trans(conf Configuration) []Configuration {
 		// (#+o) ¦- Q
 		crconf.Process = parElem.ProcessR
 		// -t-> (b+o) ¦- Q'
-		crconfs := trans(crconf)
+		crconfs := transComponent(crconf)
 
 		for _, lconf := range clconfs {
 			for _, rconf := range crconfs {
@@ -623,9 +719,8 @@ func trans(conf Configuration) []Configuration {
 					lconf.Label.Symbol2.Value == 1 &&
 					rconf.Label.Symbol.Type == SymbolTypInput &&
 					rconf.Label.Symbol2.Type == SymbolTypFreshInput &&
-					rconf.Label.Symbol2.Value == 1 &&
-					lconf.Label.Symbol.Value == rconf.Label.Symbol.Value {
-					{
+					rconf.Label.Symbol2.Value == 1 {
+					if constraint, ok := getCommConstraint(lconf, rconf); ok {
 						close := deepcopy.Copy(basePar).(Configuration)
 						lproc := deepcopy.Copy(lconf.Process).(Element)
 						rproc := deepcopy.Copy(rconf.Process).(Element)
@@ -667,6 +762,10 @@ func trans(conf Configuration) []Configuration {
 							Symbol: Symbol{
 								Type: SymbolTypTau,
 							},
+							Constraint: constraint,
+						}
+						if explainTransitions {
+							close.Provenance = derive(RuleClose, lconf.Provenance, rconf.Provenance)
 						}
 						confs = append(confs, close)
 					}
@@ -677,9 +776,8 @@ func trans(conf Configuration) []Configuration {
 					lconf.Label.Symbol2.Value == 1 &&
 					rconf.Label.Symbol.Type == SymbolTypOutput &&
 					rconf.Label.Symbol2.Type == SymbolTypFreshOutput &&
-					rconf.Label.Symbol2.Value == 1 &&
-					lconf.Label.Symbol.Value == rconf.Label.Symbol.Value {
-					{
+					rconf.Label.Symbol2.Value == 1 {
+					if constraint, ok := getCommConstraint(lconf, rconf); ok {
 						close := deepcopy.Copy(basePar).(Configuration)
 						lproc := deepcopy.Copy(lconf.Process).(Element)
 						rproc := deepcopy.Copy(rconf.Process).(Element)
@@ -721,6 +819,10 @@ func trans(conf Configuration) []Configuration {
 							Symbol: Symbol{
 								Type: SymbolTypTau,
 							},
+							Constraint: constraint,
+						}
+						if explainTransitions {
+							close.Provenance = derive(RuleClose, lconf.Provenance, rconf.Provenance)
 						}
 						confs = append(confs, close)
 					}